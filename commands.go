@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: wakey [command]
+
+Without a command wakey starts the TUI.
+
+Commands:
+  listen    Print every magic packet received on UDP ports 7 and 9
+  relay     Wake devices on this network for wakey on other networks
+  import    Import devices from /etc/ethers, DHCP leases, ip neigh, arp -a
+            or the files of wakeonlan, wol and WakeMeOnLan
+  help      Show this help
+`
+
+// runCommand runs a command given on the command line
+func runCommand(name string, args []string) error {
+	switch name {
+	case "listen":
+		return listen(args)
+	case "relay":
+		return relay(args)
+	case "import":
+		return importDevices(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %s", name)
+	}
+}
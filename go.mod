@@ -8,6 +8,7 @@ require (
 	github.com/charmbracelet/lipgloss v0.12.1
 	github.com/google/uuid v1.6.0
 	github.com/prometheus-community/pro-bing v0.4.1
+	golang.org/x/net v0.28.0
 	golang.org/x/term v0.23.0
 )
 
@@ -17,7 +18,6 @@ require (
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/muesli/termenv v0.15.2 // indirect
-	golang.org/x/net v0.28.0 // indirect
 )
 
 require (
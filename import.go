@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"wakey/internal/config"
+	"wakey/internal/importer"
+)
+
+// importDevices adds the devices in a file of another tool, like /etc/ethers
+// or DHCP leases, to the config after showing what will change.
+func importDevices(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "auto", "format of the file, auto or one of "+strings.Join(importer.FormatNames(), ", "))
+	update := flags.Bool("update", false, "update the IP address of devices that are already in the config")
+	dryRun := flags.Bool("n", false, "only show what would change")
+	yes := flags.Bool("y", false, "write the changes without asking")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: wakey import [flags] [file]")
+		fmt.Fprintln(flags.Output(), "\nReads the file, or pasted text from stdin when no file is given.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	// Read pasted text when no file is given
+	var input io.Reader = os.Stdin
+	fromStdin := flags.NArg() == 0 || flags.Arg(0) == "-"
+	if !fromStdin {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	devices, err := importer.Read(input, *format)
+	if err != nil {
+		return err
+	}
+
+	// Show what will change before anything is written
+	cfg := config.ReadConfig()
+	changes := importer.Merge(cfg, devices, *update)
+	if err := importer.Preview(os.Stdout, changes); err != nil {
+		return err
+	}
+	fmt.Printf("\n%s\n", importer.Summary(changes))
+
+	if *dryRun || importer.Count(changes, importer.ActionSkip) == len(changes) {
+		return nil
+	}
+
+	// Stdin has been read to the end, so there is no way to ask
+	if !*yes {
+		if fromStdin {
+			return fmt.Errorf("run again with -y to write the changes")
+		}
+		if !confirm("Write the changes to the config?") {
+			return nil
+		}
+	}
+
+	config.WriteConfig(importer.Apply(cfg, changes))
+	return nil
+}
+
+// confirm asks a yes or no question on the terminal
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -13,6 +13,7 @@ type KeyMap struct {
 	Delete  key.Binding
 	View    key.Binding
 	Refresh key.Binding
+	Pause   key.Binding
 	Help    key.Binding
 	Quit    key.Binding
 }
@@ -27,9 +28,9 @@ func (k KeyMap) ShortHelp() []key.Binding {
 // key.Map interface.
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Up, k.Down, k.View},                           // first column
-		{k.Enter, k.Create, k.Edit, k.Delete, k.Refresh}, // second column
-		{k.Help, k.Quit},                                 // third column
+		{k.Up, k.Down, k.View}, // first column
+		{k.Enter, k.Create, k.Edit, k.Delete, k.Refresh, k.Pause}, // second column
+		{k.Help, k.Quit}, // third column
 	}
 }
 
@@ -68,6 +69,10 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("r"),
 			key.WithHelp("r", "refresh"),
 		),
+		Pause: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "pause polling"),
+		),
 		Help: key.NewBinding(
 			key.WithKeys("ctrl+h"),
 			key.WithHelp("ctrl+h", "toggle help"),
@@ -2,6 +2,7 @@ package popup
 
 import (
 	"fmt"
+	"wakey/internal/common/refresh"
 	"wakey/internal/common/status"
 	"wakey/internal/common/style"
 
@@ -53,9 +54,7 @@ func (m PopupMsg) handleYes(handleFunc HandleFunc) (tea.Model, tea.Cmd) {
 		status.Message = action
 	}
 
-	return m.previousModel, func() tea.Msg {
-		return tea.ClearScreen()
-	}
+	return m.previousModel, tea.Batch(tea.ClearScreen, refresh.Resume(m.previousModel))
 }
 
 func (m PopupMsg) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -71,12 +70,12 @@ func (m PopupMsg) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keyMap.Yes), key.Matches(msg, m.keyMap.Enter) && m.focusIndex == 0:
 			return m.handleYes(m.handleFunc)
 		case key.Matches(msg, m.keyMap.No), key.Matches(msg, m.keyMap.Enter) && m.focusIndex == 1:
-			return m.previousModel, nil
+			return m.previousModel, refresh.Resume(m.previousModel)
 		case key.Matches(msg, m.keyMap.Help):
 			// Handle the "Help" key
 			m.help.ShowAll = !m.help.ShowAll
 		case key.Matches(msg, m.keyMap.Quit):
-			return m.previousModel, nil
+			return m.previousModel, refresh.Resume(m.previousModel)
 		}
 	}
 
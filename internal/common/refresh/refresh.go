@@ -0,0 +1,238 @@
+package refresh
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"wakey/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tickInterval is how often the poller checks if a refresh is due.
+const tickInterval = time.Second
+
+// Msg is sent for every device whose state has been refreshed
+type Msg struct {
+	Update  config.StateUpdate
+	updates <-chan config.StateUpdate
+}
+
+// DoneMsg is sent when the state of every device has been refreshed and
+// written to the config file
+type DoneMsg struct{}
+
+// TickMsg is sent every second while a poller is running
+type TickMsg struct {
+	poller *Poller
+	seq    int // the tick the poller waits for, older ticks are dropped
+}
+
+// Resumer is implemented by models that work in the background and lose
+// their ticks while another model, like a form, gets the messages.
+type Resumer interface {
+	Resume() tea.Cmd
+}
+
+// Resume returns the command that restarts the background work of the model
+// when the messages go back to it, or nil if it has none.
+func Resume(model tea.Model) tea.Cmd {
+	if r, ok := model.(Resumer); ok {
+		return r.Resume()
+	}
+	return nil
+}
+
+// Next returns a command that waits for the next device of the refresh. It
+// must be returned for every Msg to keep the results streaming in.
+func (m Msg) Next() tea.Cmd {
+	return wait(m.updates)
+}
+
+// Poller refreshes the state of the devices in the background every
+// interval. It is shared by the copies of a model, so a refresh that finishes
+// while a form is open is still recorded.
+type Poller struct {
+	ctx      context.Context // stops the polling when cancelled
+	interval time.Duration   // time between two refreshes, polling is off when 0
+
+	mu          sync.Mutex
+	running     bool      // a refresh is running
+	paused      bool      // polling is paused by the user
+	lastChecked time.Time // when the last refresh finished
+	lastTick    time.Time // when the last tick was handled
+	seq         int       // sequence number of the last tick that was sent
+}
+
+// NewPoller returns a poller that refreshes the devices every interval until
+// the context is cancelled. An interval of 0 only refreshes once.
+func NewPoller(ctx context.Context, interval time.Duration) *Poller {
+	return &Poller{ctx: ctx, interval: interval}
+}
+
+// Init returns the command that refreshes the devices right away and starts
+// the ticks of the poller.
+func (p *Poller) Init() tea.Cmd {
+	p.mu.Lock()
+	p.lastTick = time.Now()
+	tick := p.tick()
+	p.mu.Unlock()
+
+	return tea.Batch(p.Refresh(), tick)
+}
+
+// Resume returns the command that restarts the ticks of the poller, which
+// were dropped while another model got the messages. The ticks that are
+// still on their way are ignored.
+func (p *Poller) Resume() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastTick = time.Now()
+	return p.tick()
+}
+
+// Update handles the messages of the poller and returns the commands that
+// keep it going. It must be called with every message of the model.
+func (p *Poller) Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case TickMsg:
+		// Ticks of a previous poller or of a restarted one stop here
+		p.mu.Lock()
+		if msg.poller != p || msg.seq != p.seq {
+			p.mu.Unlock()
+			return nil
+		}
+
+		p.lastTick = time.Now()
+		due := p.due()
+		tick := p.tick()
+		p.mu.Unlock()
+
+		if due {
+			return tea.Batch(tick, p.Refresh())
+		}
+		return tick
+
+	case Msg:
+		return msg.Next()
+
+	default:
+		// Restart the ticks when one was lost, e.g. while a form was open
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if time.Since(p.lastTick) > 2*tickInterval {
+			p.lastTick = time.Now()
+			return p.tick()
+		}
+		return nil
+	}
+}
+
+// Refresh returns a command that refreshes the state of the devices in the
+// background, unless a refresh is already running. The devices are probed
+// concurrently and a Msg is sent for each device as soon as its probe
+// finishes, followed by a DoneMsg.
+func (p *Poller) Refresh() tea.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return nil
+	}
+	p.running = true
+
+	return func() tea.Msg {
+		source := config.StreamUpdateState(p.ctx)
+
+		// Record the end of the refresh even when nobody reads the results
+		updates := make(chan config.StateUpdate, cap(source))
+		go func() {
+			defer close(updates)
+			for update := range source {
+				updates <- update
+			}
+
+			p.mu.Lock()
+			p.running = false
+			if p.ctx.Err() == nil {
+				p.lastChecked = time.Now()
+			}
+			p.mu.Unlock()
+		}()
+
+		// Nobody is waiting for the result of a cancelled refresh
+		if p.ctx.Err() != nil {
+			return nil
+		}
+
+		return wait(updates)()
+	}
+}
+
+// TogglePause pauses or resumes the polling and reports if it is paused.
+func (p *Poller) TogglePause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused = !p.paused
+	return p.paused
+}
+
+// Status describes when the devices were last checked, e.g.
+// "last checked 12s ago (paused)".
+func (p *Poller) Status() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var s string
+	switch {
+	case p.running:
+		s = "checking…"
+	case p.lastChecked.IsZero():
+		s = "not checked yet"
+	default:
+		s = fmt.Sprintf("last checked %ds ago", int(time.Since(p.lastChecked).Seconds()))
+	}
+
+	if p.paused {
+		s += " (paused)"
+	}
+	return s
+}
+
+// due reports if the next refresh should start. It must be called with the
+// lock held.
+func (p *Poller) due() bool {
+	if p.running || p.paused || p.interval <= 0 {
+		return false
+	}
+	return time.Since(p.lastChecked) >= p.interval
+}
+
+// tick returns a command that sends a TickMsg after a second, unless the
+// poller has been stopped. It must be called with the lock held.
+func (p *Poller) tick() tea.Cmd {
+	p.seq++
+	seq := p.seq
+
+	return tea.Tick(tickInterval, func(time.Time) tea.Msg {
+		if p.ctx.Err() != nil {
+			return nil
+		}
+		return TickMsg{poller: p, seq: seq}
+	})
+}
+
+// wait returns a command that waits for the next result of the refresh.
+func wait(updates <-chan config.StateUpdate) tea.Cmd {
+	return func() tea.Msg {
+		update, ok := <-updates
+		if !ok {
+			return DoneMsg{}
+		}
+		return Msg{Update: update, updates: updates}
+	}
+}
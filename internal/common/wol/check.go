@@ -0,0 +1,200 @@
+package wol
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Probe types that can be used to check if a device is online.
+const (
+	ProbeICMP = "icmp" // Ping the device, the default
+	ProbeTCP  = "tcp"  // Connect to a TCP port of the device
+	ProbeHTTP = "http" // Send an HTTP(S) GET request and check the status code
+	ProbeARP  = "arp"  // Look up the MAC address in the ARP/NDP neighbor table
+)
+
+// ProbeTypes lists every supported probe type.
+var ProbeTypes = []string{ProbeICMP, ProbeTCP, ProbeHTTP, ProbeARP}
+
+const (
+	DefaultProbeTimeout = time.Second // Used when a check has no timeout
+	DefaultHTTPStatus   = http.StatusOK
+)
+
+// Check holds everything needed to check if a single device is online.
+type Check struct {
+	Type    string        // Optional probe type, defaults to icmp
+	Address string        // Host name or IP address of the device
+	MAC     string        // MAC address of the device for the arp probe
+	Port    int           // Port for the tcp probe, or for the http probe without a URL
+	URL     string        // Optional URL for the http probe, defaults to http://Address:Port/
+	Status  int           // Optional status code the http probe expects, defaults to 200
+	Timeout time.Duration // Optional timeout of the probe, defaults to 1s
+}
+
+// timeout returns the timeout of the check or the default if it isn't set.
+func (c Check) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DefaultProbeTimeout
+	}
+	return c.Timeout
+}
+
+// url returns the URL the http probe requests.
+func (c Check) url() string {
+	if c.URL != "" {
+		return c.URL
+	}
+
+	// IPv6 addresses are bracketed and their zones escaped,
+	// e.g. http://[fe80::1%25eth0]/
+	host := strings.ReplaceAll(c.Address, "%", "%25")
+	switch {
+	case c.Port != 0:
+		host = net.JoinHostPort(host, strconv.Itoa(c.Port))
+	case strings.Contains(host, ":"):
+		host = "[" + host + "]"
+	}
+	return "http://" + host + "/"
+}
+
+// ProbeCheck checks if the device is online with the probe type of the check.
+// Like Probe it never panics, problems are reported as the Error state with
+// the cause in Err.
+func ProbeCheck(ctx context.Context, check Check) ProbeResult {
+	switch check.Type {
+	case "", ProbeICMP:
+		return probeICMP(ctx, check.Address, check.timeout())
+	case ProbeTCP:
+		return probeTCP(ctx, check)
+	case ProbeHTTP:
+		return probeHTTP(ctx, check)
+	case ProbeARP:
+		return probeNeighbor(ctx, check)
+	default:
+		return ProbeResult{State: StateError, Err: fmt.Errorf("unknown probe type %q", check.Type)}
+	}
+}
+
+// probeTCP reports the device as online when the port accepts a connection.
+func probeTCP(ctx context.Context, check Check) ProbeResult {
+	if check.Port <= 0 || check.Port > 65535 {
+		return ProbeResult{State: StateError, Err: fmt.Errorf("invalid port %d", check.Port)}
+	}
+
+	addr, failed := resolve(ctx, check.Address)
+	if failed != nil {
+		return *failed
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, check.timeout())
+	defer cancel()
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(dialCtx, "tcp", net.JoinHostPort(addr.String(), strconv.Itoa(check.Port)))
+	rtt := time.Since(start)
+
+	switch {
+	case err == nil:
+		conn.Close()
+		return ProbeResult{State: StateOnline, RTT: rtt, IP: addr.IP}
+	case ctx.Err() != nil:
+		return ProbeResult{State: StateUnknown, Err: ctx.Err()}
+	default:
+		return ProbeResult{State: StateOffline, PacketLoss: 100, IP: addr.IP}
+	}
+}
+
+// probeHTTP reports the device as online when a GET request of the URL
+// returns the expected status code.
+func probeHTTP(ctx context.Context, check Check) ProbeResult {
+	if check.URL == "" && check.Address == "" {
+		return ProbeResult{State: StateError, Err: fmt.Errorf("%w: no address", ErrInvalidAddress)}
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, check.timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, check.url(), nil)
+	if err != nil {
+		return ProbeResult{State: StateError, Err: err}
+	}
+
+	// Resolve the host like the other probes so .local names work
+	var ip net.IP
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addr, err := LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		ip = addr.IP
+
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+	}
+	client := &http.Client{Transport: transport}
+	defer transport.CloseIdleConnections()
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	rtt := time.Since(start)
+
+	switch {
+	case err == nil:
+		resp.Body.Close()
+	case ctx.Err() != nil:
+		return ProbeResult{State: StateUnknown, Err: ctx.Err()}
+	case isTLSError(err):
+		// The device answered, but its certificate can't be verified,
+		// e.g. the self-signed certificate of a NAS
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return ProbeResult{State: StateError, Err: err, IP: ip}
+	default:
+		return ProbeResult{State: StateOffline, PacketLoss: 100}
+	}
+
+	// The device is up but not healthy
+	expected := check.Status
+	if expected == 0 {
+		expected = DefaultHTTPStatus
+	}
+	if resp.StatusCode != expected {
+		return ProbeResult{State: StateError, Err: fmt.Errorf("status %d, expected %d", resp.StatusCode, expected)}
+	}
+
+	return ProbeResult{State: StateOnline, RTT: rtt, IP: ip}
+}
+
+// isTLSError reports whether the request failed in the TLS handshake, which
+// means something answered on the port.
+func isTLSError(err error) bool {
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		alertErr     tls.AlertError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) || errors.As(err, &alertErr) || errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
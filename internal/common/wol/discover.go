@@ -0,0 +1,107 @@
+package wol
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reverseLookupTimeout limits the reverse DNS lookup of a discovered device.
+const reverseLookupTimeout = time.Second
+
+// Discovered is a device found on the network by Discover or
+// DiscoverServices.
+type Discovered struct {
+	IP       net.IP
+	MAC      net.HardwareAddr // Empty when the hardware address couldn't be found
+	Hostname string           // Host name of the device, empty when it is unknown
+	Name     string           // Name the device announced itself with
+	Services []string         // DNS-SD service types the device announced
+}
+
+// Discover sweeps the networks and returns the devices that answered, with
+// the hardware addresses from the neighbor table. Entries that haven't been
+// confirmed for a while are included, since Linux takes seconds to confirm
+// them again. The local networks are swept when no networks are given. The
+// devices are sorted by IP address.
+func Discover(ctx context.Context, networks ...*net.IPNet) ([]Discovered, error) {
+	var err error
+	if len(networks) == 0 {
+		networks, err = LocalNetworks()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := SweepNetworks(ctx, networks); err != nil {
+		return nil, err
+	}
+
+	neighbors, err := Neighbors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep one entry per hardware address within the networks
+	var discovered []Discovered
+	seen := make(map[string]bool)
+	for _, neighbor := range neighbors {
+		if !neighbor.State.Recent() || !isUnicastMAC(neighbor.MAC) || seen[neighbor.MAC.String()] {
+			continue
+		}
+		if !containsIP(networks, neighbor.IP) {
+			continue
+		}
+
+		seen[neighbor.MAC.String()] = true
+		discovered = append(discovered, Discovered{IP: neighbor.IP, MAC: neighbor.MAC})
+	}
+
+	lookupHostnames(ctx, discovered)
+
+	// Sort by IP address
+	slices.SortFunc(discovered, func(a, b Discovered) int {
+		return bytes.Compare(a.IP.To16(), b.IP.To16())
+	})
+
+	return discovered, nil
+}
+
+// lookupHostnames looks up the host names of the devices at the same time.
+func lookupHostnames(ctx context.Context, discovered []Discovered) {
+	ctx, cancel := context.WithTimeout(ctx, reverseLookupTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := range discovered {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names, err := net.DefaultResolver.LookupAddr(ctx, discovered[i].IP.String())
+			if err == nil && len(names) > 0 {
+				discovered[i].Hostname = strings.TrimSuffix(names[0], ".")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// containsIP reports whether one of the networks contains the address.
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnicastMAC reports whether the hardware address belongs to a single
+// device, which rules out broadcast, multicast and empty addresses.
+func isUnicastMAC(mac net.HardwareAddr) bool {
+	return len(mac) > 0 && mac[0]&0x01 == 0 && !bytes.Equal(mac, make(net.HardwareAddr, len(mac)))
+}
@@ -0,0 +1,293 @@
+package wol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// browseTimeout is how long Browse waits for answers when the context has no
+// deadline.
+const browseTimeout = 2 * time.Second
+
+// DefaultServiceTypes are the DNS-SD service types browsed when none are
+// given. They cover computers, file servers and printers.
+var DefaultServiceTypes = []string{
+	"_workstation._tcp",
+	"_device-info._tcp",
+	"_smb._tcp",
+	"_afpovertcp._tcp",
+	"_ssh._tcp",
+	"_rfb._tcp",
+	"_ipp._tcp",
+	"_printer._tcp",
+	"_http._tcp",
+}
+
+// reWorkstation matches the name of a _workstation._tcp service, which Avahi
+// publishes as "hostname [00:11:22:33:44:55]".
+var reWorkstation = regexp.MustCompile(`^(.*) \[([0-9a-fA-F:]{17})\]$`)
+
+// Service is a DNS-SD service instance found by Browse.
+type Service struct {
+	Instance string // Name of the instance, e.g. "Office NAS"
+	Type     string // Service type, e.g. "_smb._tcp"
+	Host     string // Host name of the device, e.g. "nas.local"
+	IP       net.IP
+	Port     int
+}
+
+// Browse sends one multicast DNS query for the service types and collects
+// the service instances that are announced until the context is done, or
+// for 2 seconds when it has no deadline. The DefaultServiceTypes are browsed
+// when no types are given. The instances found before the deadline are
+// returned, an error is only returned when the context is canceled.
+func Browse(ctx context.Context, types ...string) ([]Service, error) {
+	if len(types) == 0 {
+		types = DefaultServiceTypes
+	}
+
+	// Ask for the instances of every service type at once
+	var questions []dnsmessage.Question
+	for _, t := range types {
+		name, err := dnsmessage.NewName(t + ".local.")
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, dnsmessage.Question{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET})
+	}
+
+	query, err := (&dnsmessage.Message{Questions: questions}).Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queries from a port other than 5353 are answered to that port
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(browseTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	// Stop reading when the context is done
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := conn.WriteTo(query, group); err != nil {
+		return nil, err
+	}
+
+	// Collect every answer until the deadline
+	var responses [][]byte
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		responses = append(responses, bytes.Clone(buf[:n]))
+	}
+
+	// Passing the deadline ends the browse, only a cancel throws the answers away
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, ctx.Err()
+	}
+
+	return ParseServices(responses, types...), nil
+}
+
+// ParseServices returns the instances of the service types announced in the
+// mDNS responses, with the host, port and address found in the records of
+// all responses. Messages that aren't responses are skipped. The
+// DefaultServiceTypes are returned when no types are given.
+func ParseServices(responses [][]byte, types ...string) []Service {
+	if len(types) == 0 {
+		types = DefaultServiceTypes
+	}
+
+	records := newServiceRecords()
+	for _, response := range responses {
+		var msg dnsmessage.Message
+		if msg.Unpack(response) != nil || !msg.Header.Response {
+			continue
+		}
+		records.add(msg.Answers)
+		records.add(msg.Additionals)
+	}
+
+	return records.services(types)
+}
+
+// serviceRecords holds the records of the answers to a browse query by name.
+type serviceRecords struct {
+	instances map[string][]string  // service type to instance names
+	targets   map[string]srvTarget // instance name to host and port
+	addrs     map[string]net.IP    // host name to address
+}
+
+// srvTarget is the host and port of a service instance.
+type srvTarget struct {
+	host string
+	port int
+}
+
+func newServiceRecords() *serviceRecords {
+	return &serviceRecords{
+		instances: make(map[string][]string),
+		targets:   make(map[string]srvTarget),
+		addrs:     make(map[string]net.IP),
+	}
+}
+
+// add records the PTR, SRV, A and AAAA records. Names are stored in lower
+// case since DNS compares them without case.
+func (r *serviceRecords) add(resources []dnsmessage.Resource) {
+	for _, resource := range resources {
+		name := strings.ToLower(resource.Header.Name.String())
+
+		switch body := resource.Body.(type) {
+		case *dnsmessage.PTRResource:
+			instance := body.PTR.String()
+			if !slices.Contains(r.instances[name], instance) {
+				r.instances[name] = append(r.instances[name], instance)
+			}
+		case *dnsmessage.SRVResource:
+			r.targets[name] = srvTarget{host: strings.ToLower(body.Target.String()), port: int(body.Port)}
+		case *dnsmessage.AResource:
+			r.addrs[name] = net.IP(body.A[:])
+		case *dnsmessage.AAAAResource:
+			// Prefer IPv4 addresses
+			if r.addrs[name] == nil {
+				r.addrs[name] = net.IP(body.AAAA[:])
+			}
+		}
+	}
+}
+
+// services returns the instances of the service types with their host, port
+// and address.
+func (r *serviceRecords) services(types []string) []Service {
+	var services []Service
+	for _, t := range types {
+		suffix := "." + t + ".local."
+		for _, instance := range r.instances[strings.ToLower(t+".local.")] {
+			target := r.targets[strings.ToLower(instance)]
+			services = append(services, Service{
+				Instance: strings.TrimSuffix(instance, suffix),
+				Type:     t,
+				Host:     strings.TrimSuffix(target.host, "."),
+				IP:       r.addrs[target.host],
+				Port:     target.port,
+			})
+		}
+	}
+	return services
+}
+
+// DiscoverServices browses the service types like Browse and merges the
+// services of each device like MergeServices, with the hardware addresses of
+// the neighbor table.
+func DiscoverServices(ctx context.Context, types ...string) ([]Discovered, error) {
+	services, err := Browse(ctx, types...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The deadline of the context ends the browse, give the lookups their own
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), browseTimeout)
+		defer cancel()
+	}
+
+	// Resolve the hosts whose address wasn't in the answers
+	for i, service := range services {
+		if service.IP == nil && service.Host != "" {
+			if addr, err := LookupHost(ctx, service.Host); err == nil {
+				services[i].IP = addr.IP
+			}
+		}
+	}
+
+	neighbors, _ := Neighbors(ctx)
+
+	return MergeServices(services, neighbors), nil
+}
+
+// MergeServices returns a device for each address that announced a service,
+// named after its first service instance. The hardware address is read from
+// the name of an Avahi workstation service or looked up in the neighbors.
+// Services without an address are skipped and devices are sorted by IP
+// address.
+func MergeServices(services []Service, neighbors []Neighbor) []Discovered {
+	var discovered []Discovered
+	index := make(map[string]int)
+	for _, service := range services {
+		if service.IP == nil {
+			continue
+		}
+
+		i, ok := index[service.IP.String()]
+		if !ok {
+			i = len(discovered)
+			index[service.IP.String()] = i
+			discovered = append(discovered, Discovered{IP: service.IP, Hostname: service.Host})
+		}
+		d := &discovered[i]
+
+		// Avahi puts the hardware address in the name of the workstation
+		name := service.Instance
+		if match := reWorkstation.FindStringSubmatch(name); match != nil {
+			name = match[1]
+			if mac, err := net.ParseMAC(match[2]); err == nil && d.MAC == nil {
+				d.MAC = mac
+			}
+		}
+
+		if d.Name == "" {
+			d.Name = name
+		}
+		if !slices.Contains(d.Services, service.Type) {
+			d.Services = append(d.Services, service.Type)
+		}
+	}
+
+	// Look up the remaining hardware addresses by IP address
+	for i := range discovered {
+		if discovered[i].MAC != nil {
+			continue
+		}
+		for _, neighbor := range neighbors {
+			if neighbor.IP.Equal(discovered[i].IP) && isUnicastMAC(neighbor.MAC) {
+				discovered[i].MAC = neighbor.MAC
+				break
+			}
+		}
+	}
+
+	slices.SortFunc(discovered, func(a, b Discovered) int {
+		return bytes.Compare(a.IP.To16(), b.IP.To16())
+	})
+
+	return discovered
+}
@@ -0,0 +1,57 @@
+package wol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// sendEthernet sends the magic packet in a raw Ethernet frame with EtherType
+// 0x0842, the same way etherwake does. This needs CAP_NET_RAW.
+func sendEthernet(ctx context.Context, target Target, packet []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	iface, err := findInterface(target.Interface)
+	if err != nil {
+		return err
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(EtherType)))
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+			return fmt.Errorf("ethernet transport requires CAP_NET_RAW, run as root or use: setcap cap_net_raw+ep <path to wakey>")
+		}
+		return fmt.Errorf("opening raw socket: %v", err)
+	}
+	defer syscall.Close(fd)
+
+	// Build the frame: destination, source, EtherType and the magic packet
+	broadcast := net.HardwareAddr{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	frame := make([]byte, 0, 14+len(packet))
+	frame = append(frame, broadcast...)
+	frame = append(frame, iface.HardwareAddr...)
+	frame = append(frame, byte(EtherType>>8), byte(EtherType&0xFF))
+	frame = append(frame, packet...)
+
+	addr := syscall.SockaddrLinklayer{
+		Protocol: htons(EtherType),
+		Ifindex:  iface.Index,
+		Halen:    uint8(len(broadcast)),
+	}
+	copy(addr.Addr[:], broadcast)
+
+	if err := syscall.Sendto(fd, frame, 0, &addr); err != nil {
+		return fmt.Errorf("sending ethernet frame on %s: %v", iface.Name, err)
+	}
+
+	return nil
+}
+
+// htons converts a short from host to network byte order.
+func htons(i uint16) uint16 {
+	return i<<8 | i>>8
+}
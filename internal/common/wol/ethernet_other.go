@@ -0,0 +1,13 @@
+//go:build !linux
+
+package wol
+
+import (
+	"context"
+	"fmt"
+)
+
+// sendEthernet is only implemented on Linux since it needs AF_PACKET sockets.
+func sendEthernet(ctx context.Context, target Target, packet []byte) error {
+	return fmt.Errorf("ethernet transport is only supported on Linux")
+}
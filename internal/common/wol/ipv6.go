@@ -0,0 +1,51 @@
+package wol
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// AllNodesMulticast is the link-local all-nodes multicast address used by the
+// IPv6 transport when the target has no broadcast address.
+const AllNodesMulticast = "ff02::1"
+
+// sendIPv6 sends the magic packet to the IPv6 link-local all-nodes multicast
+// group on the interface of the target.
+func sendIPv6(ctx context.Context, target Target, packet []byte) error {
+	iface, err := findInterface(target.Interface)
+	if err != nil {
+		return err
+	}
+
+	// Use the broadcast address of the target if it is an IPv6 address
+	group := net.ParseIP(target.BroadcastAddress)
+	if group == nil || group.To4() != nil {
+		group = net.ParseIP(AllNodesMulticast)
+	}
+
+	port := target.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+
+	// Link-local multicast needs the interface as the zone
+	var dialer net.Dialer
+	addr := &net.UDPAddr{IP: group, Port: port, Zone: iface.Name}
+	conn, err := dialer.DialContext(ctx, "udp6", addr.String())
+	if err != nil {
+		return fmt.Errorf("opening IPv6 socket on %s: %v", iface.Name, err)
+	}
+	defer conn.Close()
+
+	// Don't block past the deadline of the context
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+
+	if _, err := conn.Write(packet); err != nil {
+		return fmt.Errorf("sending to %s%%%s: %v", group, iface.Name, err)
+	}
+
+	return nil
+}
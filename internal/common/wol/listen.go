@@ -0,0 +1,52 @@
+package wol
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// Listen listens for magic packets on the given UDP ports and calls handle
+// for every magic packet it receives. Other packets are ignored. It blocks
+// until a port can't be read anymore.
+func Listen(ports []int, handle func(packet *MagicPacket, from net.Addr)) error {
+	var conns []*net.UDPConn
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	// Open all ports first so a missing permission is reported right away
+	for _, port := range ports {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+		if err != nil {
+			if errors.Is(err, os.ErrPermission) {
+				return fmt.Errorf("listening on port %d requires root: %v", port, err)
+			}
+			return err
+		}
+		conns = append(conns, conn)
+	}
+
+	errs := make(chan error, len(conns))
+	for _, conn := range conns {
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				n, from, err := conn.ReadFrom(buf)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if packet, err := Parse(buf[:n]); err == nil {
+					handle(packet, from)
+				}
+			}
+		}()
+	}
+
+	return <-errs
+}
@@ -0,0 +1,152 @@
+package wol
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	mdnsAddress = "224.0.0.251:5353" // IPv4 multicast group of multicast DNS
+	mdnsTimeout = time.Second        // Used when the context has no deadline
+)
+
+// IsMDNSName reports whether the host name is resolved with multicast DNS,
+// which is the case for names in the .local domain.
+func IsMDNSName(host string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(host, ".")), ".local")
+}
+
+// LookupHost resolves the host name to an IP address. Names in the .local
+// domain are resolved with multicast DNS, other names with the system
+// resolver. IP addresses are returned as they are.
+func LookupHost(ctx context.Context, host string) (net.IPAddr, error) {
+	if IsMDNSName(host) {
+		ip, err := lookupMDNS(ctx, host)
+		return net.IPAddr{IP: ip}, err
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return net.IPAddr{}, err
+	}
+	if len(addrs) == 0 {
+		return net.IPAddr{}, fmt.Errorf("no address for %s", host)
+	}
+
+	return addrs[0], nil
+}
+
+// lookupMDNS sends a one-shot multicast DNS query for the A and AAAA records
+// of the name and returns the first address that is answered. IPv4 addresses
+// are preferred when both arrive in the same answer.
+func lookupMDNS(ctx context.Context, host string) (net.IP, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := (&dnsmessage.Message{
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+			{Name: name, Type: dnsmessage.TypeAAAA, Class: dnsmessage.ClassINET},
+		},
+	}).Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queries from a port other than 5353 are answered to that port
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(mdnsTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	// Stop reading when the context is done
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := conn.WriteTo(query, group); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("no multicast DNS answer for %s", host)
+		}
+
+		if ip := parseMDNSAnswer(buf[:n], name); ip != nil {
+			return ip, nil
+		}
+	}
+}
+
+// parseMDNSAnswer returns the address of the name in a multicast DNS answer.
+func parseMDNSAnswer(msg []byte, name dnsmessage.Name) net.IP {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(msg)
+	if err != nil || !header.Response {
+		return nil
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil
+	}
+
+	var ipv6 net.IP
+	for {
+		answer, err := parser.AnswerHeader()
+		if err != nil {
+			break
+		}
+
+		// Names are compared without case like DNS does
+		if !strings.EqualFold(answer.Name.String(), name.String()) {
+			parser.SkipAnswer()
+			continue
+		}
+
+		switch answer.Type {
+		case dnsmessage.TypeA:
+			resource, err := parser.AResource()
+			if err != nil {
+				return nil
+			}
+			return net.IP(resource.A[:])
+		case dnsmessage.TypeAAAA:
+			resource, err := parser.AAAAResource()
+			if err != nil {
+				return nil
+			}
+			if ipv6 == nil {
+				ipv6 = net.IP(resource.AAAA[:])
+			}
+		default:
+			parser.SkipAnswer()
+		}
+	}
+
+	return ipv6
+}
@@ -0,0 +1,248 @@
+package wol
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NeighborState is the state of an entry in the neighbor table.
+type NeighborState string
+
+// States of the neighbor table, named like the states of `ip neigh`.
+const (
+	NeighborReachable  NeighborState = "REACHABLE"  // The neighbor answered recently
+	NeighborStale      NeighborState = "STALE"      // The neighbor hasn't been confirmed for a while
+	NeighborDelay      NeighborState = "DELAY"      // The neighbor is about to be confirmed
+	NeighborProbe      NeighborState = "PROBE"      // The neighbor is being confirmed
+	NeighborIncomplete NeighborState = "INCOMPLETE" // The address is being resolved
+	NeighborFailed     NeighborState = "FAILED"     // The neighbor didn't answer
+	NeighborPermanent  NeighborState = "PERMANENT"  // The entry was added by hand
+	NeighborNoARP      NeighborState = "NOARP"      // The address doesn't need to be resolved
+)
+
+// Recent reports whether the neighbor has been seen recently, which rules
+// out failed and incomplete entries and entries that were added by hand.
+func (s NeighborState) Recent() bool {
+	switch s {
+	case NeighborReachable, NeighborStale, NeighborDelay, NeighborProbe:
+		return true
+	}
+	return false
+}
+
+// DefaultNeighborTimeout is used by the neighbor probe when a check has no
+// timeout. It is longer than DefaultProbeTimeout since Linux waits 5 seconds
+// before it confirms a stale neighbor.
+const DefaultNeighborTimeout = 6 * time.Second
+
+// neighborPollDelay is the delay between two reads of the neighbor table.
+const neighborPollDelay = 200 * time.Millisecond
+
+// Neighbor is an entry of the ARP or NDP neighbor table.
+type Neighbor struct {
+	IP        net.IP
+	MAC       net.HardwareAddr
+	Interface string
+	State     NeighborState
+}
+
+// NeighborTable reads the neighbor table for Neighbors. It can be replaced,
+// e.g. by tests that need a fixed table.
+var NeighborTable = neighbors
+
+// Neighbors returns the entries of the neighbor table of this machine. On
+// Linux the table is read with netlink, falling back to /proc/net/arp. On
+// other systems the output of `arp -a` is parsed.
+func Neighbors(ctx context.Context) ([]Neighbor, error) {
+	return NeighborTable(ctx)
+}
+
+// FindNeighbor returns the entry of the neighbor table with the hardware
+// address. A reachable entry is preferred when there are several.
+func FindNeighbor(neighbors []Neighbor, mac net.HardwareAddr) (Neighbor, bool) {
+	var found Neighbor
+	ok := false
+
+	for _, neighbor := range neighbors {
+		if !bytes.Equal(neighbor.MAC, mac) {
+			continue
+		}
+		if neighbor.State == NeighborReachable {
+			return neighbor, true
+		}
+		if !ok {
+			found, ok = neighbor, true
+		}
+	}
+
+	return found, ok
+}
+
+// ParseNeighbors parses a neighbor table in the format of `ip neigh` or
+// `arp -a` on Linux, macOS, BSD and Windows. Lines without both an IP and a
+// hardware address are skipped. Tables that don't have states, like the
+// output of `arp -a`, are taken as reachable.
+func ParseNeighbors(r io.Reader) ([]Neighbor, error) {
+	var neighbors []Neighbor
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		neighbor := Neighbor{State: NeighborReachable}
+		for i, field := range fields {
+			switch {
+			// ip neigh: dev eth0, arp -a: on en0
+			case (field == "dev" || field == "on") && i+1 < len(fields):
+				neighbor.Interface = fields[i+1]
+			case neighbor.IP == nil && parseNeighborIP(field) != nil:
+				neighbor.IP = parseNeighborIP(field)
+			case neighbor.MAC == nil && parseLooseMAC(field) != nil:
+				neighbor.MAC = parseLooseMAC(field)
+			default:
+				if state, ok := parseNeighborState(field); ok {
+					neighbor.State = state
+				}
+			}
+		}
+
+		if neighbor.IP != nil && neighbor.MAC != nil {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+
+	return neighbors, scanner.Err()
+}
+
+// parseNeighborIP parses an IP address that may be wrapped in parentheses
+// like in the output of `arp -a`, or have a zone like fe80::1%en0.
+func parseNeighborIP(field string) net.IP {
+	field = strings.Trim(field, "()")
+	if host, _, ok := strings.Cut(field, "%"); ok {
+		field = host
+	}
+	return net.ParseIP(field)
+}
+
+// parseLooseMAC parses a hardware address whose bytes may be written without
+// a leading zero, like macOS does (0:1a:2b:3:4:5).
+func parseLooseMAC(field string) net.HardwareAddr {
+	parts := strings.FieldsFunc(field, func(r rune) bool {
+		return strings.ContainsRune(delims, r)
+	})
+	if len(parts) != 6 && len(parts) != 8 {
+		return nil
+	}
+
+	mac := make(net.HardwareAddr, len(parts))
+	for i, part := range parts {
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil || len(part) > 2 {
+			return nil
+		}
+		mac[i] = byte(b)
+	}
+
+	return mac
+}
+
+// parseNeighborState parses the state words of `ip neigh` and `arp -a`.
+func parseNeighborState(field string) (NeighborState, bool) {
+	switch strings.ToLower(field) {
+	case "reachable", "dynamic":
+		return NeighborReachable, true
+	case "stale":
+		return NeighborStale, true
+	case "delay":
+		return NeighborDelay, true
+	case "probe":
+		return NeighborProbe, true
+	case "incomplete", "(incomplete)":
+		return NeighborIncomplete, true
+	case "failed":
+		return NeighborFailed, true
+	case "permanent", "static":
+		return NeighborPermanent, true
+	case "noarp":
+		return NeighborNoARP, true
+	}
+	return "", false
+}
+
+// touch sends an empty UDP packet to the discard port of the address so the
+// neighbor table resolves or confirms its hardware address.
+func touch(ctx context.Context, address string) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(address, strconv.Itoa(DefaultPort)))
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	conn.Write(nil)
+}
+
+// probeNeighbor reports the device as online when its hardware address is
+// reachable in the neighbor table. The IP address of the entry is returned,
+// which may differ from the address of the check when the device got a new
+// address. `arp -a` has no states, so on systems other than Linux every
+// entry in the table counts as reachable, also the ones that are only cached.
+func probeNeighbor(ctx context.Context, check Check) ProbeResult {
+	mac := parseLooseMAC(check.MAC)
+	if mac == nil {
+		return ProbeResult{State: StateError, Err: fmt.Errorf("invalid mac address %q", check.MAC)}
+	}
+
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultNeighborTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	// Make the neighbor table confirm the last known address
+	touched := make(map[string]bool)
+	if check.Address != "" {
+		touch(ctx, check.Address)
+		touched[check.Address] = true
+	}
+
+	for {
+		neighbors, err := Neighbors(ctx)
+		if ctx.Err() != nil {
+			return ProbeResult{State: StateUnknown, Err: ctx.Err()}
+		}
+		if err != nil {
+			return ProbeResult{State: StateError, Err: err}
+		}
+
+		neighbor, found := FindNeighbor(neighbors, mac)
+		if found && neighbor.State == NeighborReachable {
+			return ProbeResult{State: StateOnline, IP: neighbor.IP}
+		}
+
+		// The device may have a new address that nothing confirms, like after
+		// DHCP moved it, so make the neighbor table confirm that one too
+		if found && !touched[neighbor.IP.String()] {
+			touch(ctx, neighbor.IP.String())
+			touched[neighbor.IP.String()] = true
+		}
+
+		// Give up once the timeout has passed
+		if time.Now().Add(neighborPollDelay).After(deadline) {
+			return ProbeResult{State: StateOffline, PacketLoss: 100, IP: neighbor.IP}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ProbeResult{State: StateUnknown, Err: ctx.Err()}
+		case <-time.After(neighborPollDelay):
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package wol
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Neighbor states of the Linux kernel, see include/uapi/linux/neighbour.h.
+const (
+	nudIncomplete = 0x01
+	nudReachable  = 0x02
+	nudStale      = 0x04
+	nudDelay      = 0x08
+	nudProbe      = 0x10
+	nudFailed     = 0x20
+	nudNoARP      = 0x40
+	nudPermanent  = 0x80
+)
+
+// Attributes of a neighbor message.
+const (
+	ndaDst    = 1 // IP address
+	ndaLLAddr = 2 // Hardware address
+)
+
+// ndmsgLen is the size of struct ndmsg that starts a neighbor message.
+const ndmsgLen = 12
+
+// atfComplete is the flag of a resolved entry in /proc/net/arp.
+const atfComplete = 0x2
+
+// neighbors reads the neighbor table with netlink, which includes IPv6
+// neighbors and their states, and falls back to /proc/net/arp.
+func neighbors(ctx context.Context) ([]Neighbor, error) {
+	neighbors, err := netlinkNeighbors()
+	if err != nil {
+		return procNeighbors()
+	}
+	return neighbors, nil
+}
+
+// netlinkNeighbors dumps the neighbor table with an RTM_GETNEIGH request.
+func netlinkNeighbors() ([]Neighbor, error) {
+	data, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make(map[int]string)
+
+	var neighbors []Neighbor
+	for _, msg := range msgs {
+		if msg.Header.Type != syscall.RTM_NEWNEIGH || len(msg.Data) < ndmsgLen {
+			continue
+		}
+
+		index := int(int32(binary.NativeEndian.Uint32(msg.Data[4:8])))
+		neighbor := Neighbor{State: nudState(binary.NativeEndian.Uint16(msg.Data[8:10]))}
+
+		// Look up the name of each interface once
+		name, ok := interfaces[index]
+		if !ok {
+			if iface, err := net.InterfaceByIndex(index); err == nil {
+				name = iface.Name
+			}
+			interfaces[index] = name
+		}
+		neighbor.Interface = name
+
+		// Walk the route attributes, each is aligned to 4 bytes
+		attrs := msg.Data[ndmsgLen:]
+		for len(attrs) >= 4 {
+			length := int(binary.NativeEndian.Uint16(attrs[0:2]))
+			kind := binary.NativeEndian.Uint16(attrs[2:4])
+			if length < 4 || length > len(attrs) {
+				break
+			}
+
+			value := attrs[4:length]
+			switch kind {
+			case ndaDst:
+				neighbor.IP = net.IP(append([]byte(nil), value...))
+			case ndaLLAddr:
+				neighbor.MAC = net.HardwareAddr(append([]byte(nil), value...))
+			}
+
+			attrs = attrs[min((length+3)&^3, len(attrs)):]
+		}
+
+		if neighbor.IP != nil && len(neighbor.MAC) > 0 {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+
+	return neighbors, nil
+}
+
+// nudState converts the state of the kernel to a NeighborState.
+func nudState(state uint16) NeighborState {
+	switch {
+	case state&nudReachable != 0:
+		return NeighborReachable
+	case state&nudStale != 0:
+		return NeighborStale
+	case state&nudDelay != 0:
+		return NeighborDelay
+	case state&nudProbe != 0:
+		return NeighborProbe
+	case state&nudIncomplete != 0:
+		return NeighborIncomplete
+	case state&nudFailed != 0:
+		return NeighborFailed
+	case state&nudPermanent != 0:
+		return NeighborPermanent
+	case state&nudNoARP != 0:
+		return NeighborNoARP
+	}
+	return ""
+}
+
+// procNeighbors reads the IPv4 neighbor table from /proc/net/arp. The file
+// doesn't have states, so complete entries are taken as reachable.
+func procNeighbors() ([]Neighbor, error) {
+	file, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var neighbors []Neighbor
+
+	// IP address, HW type, Flags, HW address, Mask, Device
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		mac, err := net.ParseMAC(fields[3])
+		flags, _ := strconv.ParseUint(fields[2], 0, 32)
+		if ip == nil || err != nil {
+			continue
+		}
+
+		state := NeighborIncomplete
+		if flags&atfComplete != 0 {
+			state = NeighborReachable
+		}
+
+		neighbors = append(neighbors, Neighbor{IP: ip, MAC: mac, Interface: fields[5], State: state})
+	}
+
+	return neighbors, scanner.Err()
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package wol
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// neighbors reads the neighbor table from the output of `arp -a`.
+func neighbors(ctx context.Context) ([]Neighbor, error) {
+	// Windows doesn't resolve names, the -n flag is only known on Unix
+	args := []string{"-an"}
+	if runtime.GOOS == "windows" {
+		args = []string{"-a"}
+	}
+
+	out, err := exec.CommandContext(ctx, "arp", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error reading the neighbor table: %v", err)
+	}
+
+	return ParseNeighbors(bytes.NewReader(out))
+}
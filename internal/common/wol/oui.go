@@ -0,0 +1,122 @@
+package wol
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// embeddedOUI is an oui.txt compressed with gzip. go generate replaces it
+// with the current IEEE MA-L registry, and leaves it as it is when the
+// download fails. A newer oui.txt can be loaded with LoadOUIFile.
+//
+//go:generate sh -c "set -e; trap 'rm -f oui.txt.tmp' EXIT; curl -sSfL -o oui.txt.tmp https://standards-oui.ieee.org/oui/oui.txt; grep -q '(hex)' oui.txt.tmp; gzip -9n < oui.txt.tmp > oui.txt.gz"
+//go:embed oui.txt.gz
+var embeddedOUI []byte
+
+// reOUI matches an assignment in the IEEE oui.txt format, e.g.
+// "00-11-32   (hex)		Synology Incorporated".
+var reOUI = regexp.MustCompile(`^([0-9A-Fa-f]{2})-([0-9A-Fa-f]{2})-([0-9A-Fa-f]{2})\s+\(hex\)\s+(.+)$`)
+
+var (
+	ouiOnce    sync.Once
+	ouiMu      sync.RWMutex
+	ouiVendors map[string]string // vendors by OUI, e.g. "001132"
+)
+
+// ParseOUI reads assignments in the IEEE oui.txt format and returns the
+// vendors by OUI in upper case hex, e.g. "001132". Lines that aren't
+// assignments are skipped.
+func ParseOUI(r io.Reader) (map[string]string, error) {
+	vendors := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := reOUI.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		vendors[strings.ToUpper(match[1]+match[2]+match[3])] = strings.TrimSpace(match[4])
+	}
+
+	return vendors, scanner.Err()
+}
+
+// LoadOUI adds the assignments in the IEEE oui.txt format to the vendors
+// that Vendor looks up. Assignments that are already known are replaced.
+func LoadOUI(r io.Reader) error {
+	vendors, err := ParseOUI(r)
+	if err != nil {
+		return err
+	}
+	if len(vendors) == 0 {
+		return errors.New("no OUI assignments found")
+	}
+
+	loadEmbeddedOUI()
+
+	ouiMu.Lock()
+	defer ouiMu.Unlock()
+	for oui, vendor := range vendors {
+		ouiVendors[oui] = vendor
+	}
+	return nil
+}
+
+// LoadOUIFile loads an oui.txt file downloaded from the IEEE like LoadOUI,
+// so vendors that aren't embedded can be looked up.
+func LoadOUIFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := LoadOUI(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEmbeddedOUI parses the embedded assignments the first time vendors
+// are needed.
+func loadEmbeddedOUI() {
+	ouiOnce.Do(func() {
+		var vendors map[string]string
+		if zr, err := gzip.NewReader(bytes.NewReader(embeddedOUI)); err == nil {
+			vendors, _ = ParseOUI(zr)
+		}
+		if vendors == nil {
+			vendors = make(map[string]string)
+		}
+
+		ouiMu.Lock()
+		ouiVendors = vendors
+		ouiMu.Unlock()
+	})
+}
+
+// Vendor returns the vendor the MAC address is assigned to, or an empty
+// string if the vendor isn't known or the address is locally administered,
+// like the random addresses of phones.
+func Vendor(mac string) string {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil || hwAddr[0]&0x02 != 0 {
+		return ""
+	}
+
+	loadEmbeddedOUI()
+
+	ouiMu.RLock()
+	defer ouiMu.RUnlock()
+	return ouiVendors[fmt.Sprintf("%02X%02X%02X", hwAddr[0], hwAddr[1], hwAddr[2])]
+}
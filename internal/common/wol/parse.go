@@ -0,0 +1,73 @@
+package wol
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+)
+
+// Parse decodes and validates a magic packet.
+func Parse(data []byte) (*MagicPacket, error) {
+	var packet MagicPacket
+	if err := packet.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return &packet, nil
+}
+
+// Unmarshal decodes a magic packet created by Marshal. The size of the
+// hardware address and the SecureOn password are detected from the length of
+// the data.
+func (mp *MagicPacket) Unmarshal(data []byte) error {
+	if len(data) < len(mp.header) || !bytes.Equal(data[:len(mp.header)], bytes.Repeat([]byte{0xFF}, len(mp.header))) {
+		return fmt.Errorf("not a magic packet: missing 0xFF header")
+	}
+	payload := data[len(mp.header):]
+
+	// Try MAC-48 and EUI-64 addresses followed by no, a 4 or a 6 byte password
+	for _, size := range []int{6, 8} {
+		password := len(payload) - 16*size
+		if password != 0 && password != 4 && password != 6 {
+			continue
+		}
+
+		// Every repetition must be the same address
+		hwAddr := payload[:size]
+		if !bytes.Equal(payload[:16*size], bytes.Repeat(hwAddr, 16)) {
+			return fmt.Errorf("not a magic packet: address is not repeated 16 times")
+		}
+
+		copy(mp.header[:], data[:len(mp.header)])
+		mp.hwAddr = net.HardwareAddr(bytes.Clone(hwAddr))
+		mp.password = nil
+		if password > 0 {
+			mp.password = bytes.Clone(payload[16*size:])
+		}
+		return nil
+	}
+
+	return fmt.Errorf("not a magic packet: unexpected length %d", len(data))
+}
+
+// HardwareAddr returns the hardware address the magic packet wakes.
+func (mp *MagicPacket) HardwareAddr() net.HardwareAddr {
+	return mp.hwAddr
+}
+
+// Password returns the SecureOn password of the magic packet, or nil if it
+// has none.
+func (mp *MagicPacket) Password() []byte {
+	return mp.password
+}
+
+// FormatPassword formats a SecureOn password the way ParsePassword reads it.
+func FormatPassword(password []byte) string {
+	switch len(password) {
+	case 0:
+		return ""
+	case 4:
+		return net.IP(password).String()
+	default:
+		return net.HardwareAddr(password).String()
+	}
+}
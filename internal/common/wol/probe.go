@@ -0,0 +1,119 @@
+package wol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+
+	probing "github.com/prometheus-community/pro-bing"
+)
+
+// ProbeState is the state of a device found by a probe.
+type ProbeState string
+
+// States a probe can find a device in.
+const (
+	StateOnline  ProbeState = "Online"  // The device answered
+	StateOffline ProbeState = "Offline" // The device didn't answer
+	StateUnknown ProbeState = "Unknown" // The probe was cancelled before it finished
+	StateError   ProbeState = "Error"   // The device couldn't be probed
+)
+
+// ErrInvalidAddress is the cause of a probe for an address that can't be
+// parsed or resolved.
+var ErrInvalidAddress = errors.New("invalid address")
+
+// ProbeResult is the outcome of probing a device.
+type ProbeResult struct {
+	State      ProbeState
+	RTT        time.Duration // Average round trip time when online
+	PacketLoss float64       // Percentage of probes without an answer
+	Err        error         // Cause of the Error state
+	IP         net.IP        // Address the device answered on, if the probe found it
+}
+
+// String returns the state for display, e.g. "Online" or
+// "Error: invalid address".
+func (r ProbeResult) String() string {
+	if r.State != StateError || r.Err == nil {
+		return string(r.State)
+	}
+
+	// Keep known causes short
+	if errors.Is(r.Err, ErrInvalidAddress) {
+		return fmt.Sprintf("%s: %v", r.State, ErrInvalidAddress)
+	}
+	return fmt.Sprintf("%s: %v", r.State, r.Err)
+}
+
+func checkOS() string {
+	return runtime.GOOS
+}
+
+// Probe pings the device and reports its state. It never panics, problems
+// are reported as the Error state with the cause in Err.
+func Probe(ctx context.Context, ip string) ProbeResult {
+	return probeICMP(ctx, ip, DefaultProbeTimeout)
+}
+
+// resolve looks up the address of the host with the context so a slow
+// resolver can be cancelled. A failed lookup is returned as a result.
+func resolve(ctx context.Context, host string) (net.IPAddr, *ProbeResult) {
+	if host == "" {
+		return net.IPAddr{}, &ProbeResult{State: StateError, Err: fmt.Errorf("%w: no address", ErrInvalidAddress)}
+	}
+
+	addr, err := LookupHost(ctx, host)
+	if ctx.Err() != nil {
+		return net.IPAddr{}, &ProbeResult{State: StateUnknown, Err: ctx.Err()}
+	}
+	if err != nil {
+		return net.IPAddr{}, &ProbeResult{State: StateError, Err: fmt.Errorf("%w: %s", ErrInvalidAddress, host)}
+	}
+
+	return addr, nil
+}
+
+// probeICMP pings the device once and waits for the answer until the timeout.
+func probeICMP(ctx context.Context, ip string, timeout time.Duration) ProbeResult {
+
+	// Get OS
+	userOS := checkOS()
+
+	addr, failed := resolve(ctx, ip)
+	if failed != nil {
+		return *failed
+	}
+
+	pinger := probing.New(ip)
+	pinger.SetIPAddr(&addr)
+
+	// Check if the user is using Windows
+	// If the user is using Windows, use then set the pinger to use the Windows implementation
+	// If the user is not using Windows, use the default implementation
+	if userOS == "windows" {
+		pinger.SetPrivileged(true)
+	}
+
+	pinger.Count = 1
+	pinger.Timeout = timeout
+
+	// blocks until finished or cancelled
+	if err := pinger.RunWithContext(ctx); err != nil && ctx.Err() == nil {
+		return ProbeResult{State: StateError, Err: err}
+	}
+
+	stats := pinger.Statistics() // get send/receive/rtt stats
+
+	switch {
+	case stats.PacketsRecv > 0:
+		return ProbeResult{State: StateOnline, RTT: stats.AvgRtt, PacketLoss: stats.PacketLoss, IP: addr.IP}
+	case ctx.Err() != nil:
+		return ProbeResult{State: StateUnknown, Err: ctx.Err()}
+	default:
+		return ProbeResult{State: StateOffline, PacketLoss: 100, IP: addr.IP}
+	}
+}
@@ -0,0 +1,261 @@
+package wol
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	relayMaxAge      = 30 * time.Second // How old a relay request may be
+	relayTimeout     = 10 * time.Second // How long a relay connection may take
+	relayMaxRequest  = 64 * 1024        // Maximum size of a relay request
+	DefaultRelayPort = 4343             // Port the relay listens on by default
+)
+
+// relayRequest asks a relay to wake a device on its network.
+type relayRequest struct {
+	MacAddress       string        `json:"mac"`
+	Password         string        `json:"password,omitempty"`
+	BroadcastAddress string        `json:"broadcast,omitempty"`
+	Port             int           `json:"port,omitempty"`
+	Transport        string        `json:"transport,omitempty"`
+	Repeat           int           `json:"repeat,omitempty"`
+	Interval         time.Duration `json:"interval,omitempty"`
+	Timestamp        int64         `json:"timestamp"`
+	Nonce            string        `json:"nonce"`
+}
+
+// relayMessage is a relay request signed with the shared secret.
+type relayMessage struct {
+	Request   json.RawMessage `json:"request"`
+	Signature string          `json:"signature"`
+}
+
+// relayResponse tells the client whether the relay woke the device.
+type relayResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// sign returns the HMAC-SHA256 signature of the data.
+func sign(secret string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendRelay asks the relay of the target to send the magic packet on its
+// network.
+func sendRelay(ctx context.Context, target Target) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	request, err := json.Marshal(relayRequest{
+		MacAddress:       target.MacAddress,
+		Password:         target.Password,
+		BroadcastAddress: target.BroadcastAddress,
+		Port:             target.Port,
+		Transport:        target.Transport,
+		Repeat:           target.Repeat,
+		Interval:         target.Interval,
+		Timestamp:        time.Now().Unix(),
+		Nonce:            hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Use the default port if the relay address has none
+	addr := target.Relay
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(DefaultRelayPort))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, relayTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connecting to relay %s: %v", target.Relay, err)
+	}
+	defer conn.Close()
+
+	// The deadline covers the timeout and the deadline of the caller
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	// Send the signed request and wait for the answer
+	message := relayMessage{Request: request, Signature: sign(target.RelaySecret, request)}
+	if err := json.NewEncoder(conn).Encode(message); err != nil {
+		return fmt.Errorf("sending to relay %s: %v", target.Relay, err)
+	}
+
+	var response relayResponse
+	if err := json.NewDecoder(io.LimitReader(conn, relayMaxRequest)).Decode(&response); err != nil {
+		return fmt.Errorf("reading from relay %s: %v", target.Relay, err)
+	}
+
+	if response.Error != "" {
+		return fmt.Errorf("relay %s: %s", target.Relay, response.Error)
+	}
+
+	return nil
+}
+
+// Relay wakes devices on its own network for authenticated requests from
+// other networks, e.g. over a VPN where broadcasts don't reach the LAN.
+type Relay struct {
+	Secret    string                           // Shared secret requests are signed with
+	Interface string                           // Optional interface the magic packets are sent from
+	Sender    Sender                           // Optional sender, defaults to TransportSender
+	Logf      func(format string, args ...any) // Optional logger for every request
+
+	mu     sync.Mutex
+	nonces map[string]time.Time // nonces seen recently, to reject replayed requests
+}
+
+// ListenAndServe accepts wake requests on the TCP address until the listener
+// fails.
+func (r *Relay) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return r.Serve(listener)
+}
+
+// Serve accepts wake requests on the listener until it fails.
+func (r *Relay) Serve(listener net.Listener) error {
+	defer listener.Close()
+
+	if r.Secret == "" {
+		return fmt.Errorf("relay secret is required")
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go r.handle(conn)
+	}
+}
+
+// handle reads a single request from the connection and answers it.
+func (r *Relay) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(relayTimeout))
+
+	var response relayResponse
+	target, err := r.readRequest(conn)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+		err = WakeDeviceContext(ctx, r.sender(), target)
+		cancel()
+	}
+
+	if err != nil {
+		response.Error = err.Error()
+		r.logf("%s: %v", conn.RemoteAddr(), err)
+	} else {
+		r.logf("%s: woke %s", conn.RemoteAddr(), target.MacAddress)
+	}
+
+	json.NewEncoder(conn).Encode(response)
+}
+
+// readRequest reads and authenticates a request and returns its target.
+func (r *Relay) readRequest(conn net.Conn) (Target, error) {
+	var message relayMessage
+	if err := json.NewDecoder(io.LimitReader(conn, relayMaxRequest)).Decode(&message); err != nil {
+		return Target{}, fmt.Errorf("invalid request: %v", err)
+	}
+
+	// Check the signature before looking at the request
+	if !hmac.Equal([]byte(message.Signature), []byte(sign(r.Secret, message.Request))) {
+		return Target{}, errors.New("invalid signature")
+	}
+
+	var request relayRequest
+	if err := json.Unmarshal(message.Request, &request); err != nil {
+		return Target{}, fmt.Errorf("invalid request: %v", err)
+	}
+
+	// Reject old and replayed requests
+	age := time.Since(time.Unix(request.Timestamp, 0))
+	if age > relayMaxAge || age < -relayMaxAge {
+		return Target{}, errors.New("request expired, check the clocks of both machines")
+	}
+	if !r.useNonce(request.Nonce) {
+		return Target{}, errors.New("request was already used")
+	}
+
+	// The packet is sent on the network of the relay
+	return Target{
+		MacAddress:       request.MacAddress,
+		Password:         request.Password,
+		BroadcastAddress: request.BroadcastAddress,
+		Port:             request.Port,
+		Interface:        r.Interface,
+		Transport:        request.Transport,
+		Repeat:           request.Repeat,
+		Interval:         request.Interval,
+	}, nil
+}
+
+// useNonce records the nonce and reports whether it wasn't used before.
+func (r *Relay) useNonce(nonce string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nonces == nil {
+		r.nonces = make(map[string]time.Time)
+	}
+
+	// Forget nonces of requests that are too old to be accepted anyway
+	for n, seen := range r.nonces {
+		if time.Since(seen) > 2*relayMaxAge {
+			delete(r.nonces, n)
+		}
+	}
+
+	if nonce == "" {
+		return false
+	}
+	if _, ok := r.nonces[nonce]; ok {
+		return false
+	}
+
+	r.nonces[nonce] = time.Now()
+	return true
+}
+
+// sender returns the sender of the relay.
+func (r *Relay) sender() Sender {
+	if r.Sender == nil {
+		return TransportSender{}
+	}
+	return r.Sender
+}
+
+// logf logs a message if the relay has a logger.
+func (r *Relay) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
+}
@@ -0,0 +1,101 @@
+package wol
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Sender sends a marshalled magic packet to a target once.
+type Sender interface {
+	Send(ctx context.Context, target Target, packet []byte) error
+}
+
+// TransportSender sends packets through the relay of the target if it has
+// one, otherwise with the transport of the target. It is the sender used by
+// wakey.
+type TransportSender struct{}
+
+// Send sends the packet with the sender for the target.
+func (TransportSender) Send(ctx context.Context, target Target, packet []byte) error {
+	if target.Relay != "" {
+		return RelaySender{}.Send(ctx, target, packet)
+	}
+
+	switch target.Transport {
+	case "", TransportUDP:
+		return UDPSender{}.Send(ctx, target, packet)
+	case TransportEthernet:
+		return EthernetSender{}.Send(ctx, target, packet)
+	case TransportIPv6:
+		return IPv6Sender{}.Send(ctx, target, packet)
+	default:
+		return fmt.Errorf("unknown transport %s", target.Transport)
+	}
+}
+
+// UDPSender sends packets to the broadcast address of the target.
+type UDPSender struct{}
+
+// Send sends the packet as a UDP broadcast.
+func (UDPSender) Send(ctx context.Context, target Target, packet []byte) error {
+	return sendUDP(ctx, target, packet)
+}
+
+// EthernetSender sends packets in raw Ethernet frames.
+type EthernetSender struct{}
+
+// Send sends the packet in a raw Ethernet frame.
+func (EthernetSender) Send(ctx context.Context, target Target, packet []byte) error {
+	return sendEthernet(ctx, target, packet)
+}
+
+// IPv6Sender sends packets to the IPv6 all-nodes multicast address.
+type IPv6Sender struct{}
+
+// Send sends the packet to the IPv6 multicast group.
+func (IPv6Sender) Send(ctx context.Context, target Target, packet []byte) error {
+	return sendIPv6(ctx, target, packet)
+}
+
+// RelaySender asks the relay of the target to send the packet on its network.
+type RelaySender struct{}
+
+// Send sends a wake request for the target to its relay. The relay builds
+// the packet itself.
+func (RelaySender) Send(ctx context.Context, target Target, packet []byte) error {
+	return sendRelay(ctx, target)
+}
+
+// RecordedPacket is a packet that was sent to a Recorder.
+type RecordedPacket struct {
+	Target Target
+	Packet []byte
+}
+
+// Recorder is a Sender that records packets instead of sending them, so tests
+// can check what would have been sent without touching the network.
+type Recorder struct {
+	Err error // Returned by Send if set
+
+	mu      sync.Mutex
+	packets []RecordedPacket
+}
+
+// Send records the packet.
+func (r *Recorder) Send(ctx context.Context, target Target, packet []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.packets = append(r.packets, RecordedPacket{Target: target, Packet: bytes.Clone(packet)})
+	return r.Err
+}
+
+// Packets returns the packets recorded so far.
+func (r *Recorder) Packets() []RecordedPacket {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RecordedPacket(nil), r.packets...)
+}
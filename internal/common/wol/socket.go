@@ -0,0 +1,117 @@
+package wol
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// dial opens a broadcast enabled UDP socket to the target's broadcast address.
+// If the target has an interface set, the socket is bound to it so the packet
+// leaves through that network card.
+func dial(ctx context.Context, target Target) (*net.UDPConn, error) {
+	// Get the local address to bind to
+	laddr, err := localAddr(target.Interface)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := net.Dialer{
+		LocalAddr: laddr,
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = setSockopts(fd, target.Interface)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "udp4", target.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.(*net.UDPConn), nil
+}
+
+// localAddr returns the local address for an interface name or IP address.
+// An empty string returns nil so the operating system picks the address.
+func localAddr(iface string) (*net.UDPAddr, error) {
+	if iface == "" {
+		return nil, nil
+	}
+
+	// The interface can also be given as a local IP address
+	if ip := net.ParseIP(iface); ip != nil {
+		return &net.UDPAddr{IP: ip}, nil
+	}
+
+	netIface, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %v", iface, err)
+	}
+
+	addrs, err := netIface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %v", iface, err)
+	}
+
+	// Use the first IPv4 address of the interface
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return &net.UDPAddr{IP: ipNet.IP}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("interface %s has no IPv4 address", iface)
+}
+
+// findInterface returns the interface for an interface name or local IP
+// address. An empty string returns the first interface that is up, isn't a
+// loopback and has a MAC address.
+func findInterface(iface string) (*net.Interface, error) {
+	if iface != "" && net.ParseIP(iface) == nil {
+		netIface, err := net.InterfaceByName(iface)
+		if err != nil {
+			return nil, fmt.Errorf("interface %s: %v", iface, err)
+		}
+		return netIface, nil
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, netIface := range ifaces {
+		if netIface.Flags&net.FlagUp == 0 || netIface.Flags&net.FlagLoopback != 0 || len(netIface.HardwareAddr) == 0 {
+			continue
+		}
+
+		// Without an address any interface will do
+		if iface == "" {
+			return &netIface, nil
+		}
+
+		// Otherwise look for the interface that has the address
+		addrs, err := netIface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(net.ParseIP(iface)) {
+				return &netIface, nil
+			}
+		}
+	}
+
+	if iface == "" {
+		return nil, fmt.Errorf("no usable network interface found")
+	}
+	return nil, fmt.Errorf("no interface has the address %s", iface)
+}
@@ -0,0 +1,26 @@
+package wol
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// setSockopts enables broadcasting on the socket and binds it to the
+// interface so the packet can't leave through another network card.
+func setSockopts(fd uintptr, iface string) error {
+	if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); err != nil {
+		return fmt.Errorf("enabling broadcast: %v", err)
+	}
+
+	// Local IP addresses are handled by binding to the address instead
+	if iface == "" || net.ParseIP(iface) != nil {
+		return nil
+	}
+
+	if err := syscall.BindToDevice(int(fd), iface); err != nil {
+		return fmt.Errorf("binding to interface %s: %v", iface, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package wol
+
+// setSockopts is a no-op on platforms without socket options.
+func setSockopts(fd uintptr, iface string) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build unix && !linux
+
+package wol
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setSockopts enables broadcasting on the socket. The interface is selected by
+// binding to its address since there is no portable SO_BINDTODEVICE.
+func setSockopts(fd uintptr, iface string) error {
+	if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); err != nil {
+		return fmt.Errorf("enabling broadcast: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package wol
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setSockopts enables broadcasting on the socket. The interface is selected by
+// binding to its address.
+func setSockopts(fd uintptr, iface string) error {
+	if err := syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); err != nil {
+		return fmt.Errorf("enabling broadcast: %v", err)
+	}
+
+	return nil
+}
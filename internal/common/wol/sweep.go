@@ -0,0 +1,143 @@
+package wol
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// maxSweepPrefix is the shortest prefix of a local network that is swept
+// completely. Larger networks are only swept around the local address.
+const maxSweepPrefix = 22
+
+// maxSweepBits is the number of host bits of the largest network that can be
+// swept. Larger sweeps overflow the neighbor table, which holds 1024 entries
+// by default on Linux, and the devices beyond it are never found.
+const maxSweepBits = 32 - maxSweepPrefix
+
+// MaxSweepHosts is the number of addresses of the largest network that can
+// be swept, a /22.
+const MaxSweepHosts = 1 << maxSweepBits
+
+// sweepSettle is how long Sweep waits for the answers of the devices.
+const sweepSettle = 500 * time.Millisecond
+
+// Sweep sends an empty UDP packet to every address of the local IPv4
+// networks, so the neighbor table learns the hardware addresses of the
+// devices that are up. Networks larger than a /22 are only swept in the /22
+// around the local address.
+func Sweep(ctx context.Context) error {
+	networks, err := LocalNetworks()
+	if err != nil {
+		return err
+	}
+
+	return SweepNetworks(ctx, networks)
+}
+
+// SweepNetworks sweeps the networks like Sweep. Networks with more than
+// MaxSweepHosts addresses are refused.
+func SweepNetworks(ctx context.Context, networks []*net.IPNet) error {
+	for _, network := range networks {
+		if ones, bits := network.Mask.Size(); bits != 32 || bits-ones > maxSweepBits {
+			return fmt.Errorf("%s is not an IPv4 network of at most %d addresses", network, MaxSweepHosts)
+		}
+	}
+
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, network := range networks {
+		for _, ip := range hosts(network) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			// Unanswered addresses are expected, so errors are ignored
+			conn.WriteTo(nil, &net.UDPAddr{IP: ip, Port: DefaultPort})
+		}
+	}
+
+	// Give the devices time to answer the neighbor requests
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(sweepSettle):
+	}
+
+	return nil
+}
+
+// LocalNetworks returns the IPv4 networks of the interfaces that are up,
+// without the loopback interface. Networks larger than a /22 are narrowed to
+// the /22 around the local address.
+func LocalNetworks() ([]*net.IPNet, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var networks []*net.IPNet
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil {
+				continue
+			}
+
+			// IPv4 masks can be stored in 16 bytes
+			mask := ipNet.Mask
+			if len(mask) == net.IPv6len {
+				mask = mask[12:]
+			}
+
+			// Limit large networks to the addresses around our own
+			if ones, _ := mask.Size(); ones < maxSweepPrefix {
+				mask = net.CIDRMask(maxSweepPrefix, 32)
+			}
+
+			networks = append(networks, &net.IPNet{IP: ipNet.IP.To4(), Mask: mask})
+		}
+	}
+
+	return networks, nil
+}
+
+// hosts returns the host addresses of the network without the network and
+// broadcast address and the local address itself.
+func hosts(network *net.IPNet) []net.IP {
+	ones, bits := network.Mask.Size()
+	if bits != 32 || ones > 30 {
+		return nil
+	}
+
+	first := binary.BigEndian.Uint32(network.IP.Mask(network.Mask).To4())
+	last := first | ^binary.BigEndian.Uint32(network.Mask)
+	self := binary.BigEndian.Uint32(network.IP.To4())
+
+	var ips []net.IP
+	for n := first + 1; n < last; n++ {
+		if n == self {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, n)
+		ips = append(ips, ip)
+	}
+
+	return ips
+}
@@ -0,0 +1,62 @@
+package wol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned by WakeAndWait when the device doesn't come online
+// before the timeout.
+var ErrTimeout = errors.New("device did not come online")
+
+const (
+	minPollDelay = 500 * time.Millisecond // First delay between two pings
+	maxPollDelay = 5 * time.Second        // Longest delay between two pings
+)
+
+// WakeAndWait wakes the device and pings its IP address with an increasing
+// delay until it is online or the timeout has passed. It returns how long the
+// device took to come online.
+func WakeAndWait(sender Sender, target Target, ip string, timeout time.Duration) (time.Duration, error) {
+	return WakeAndWaitContext(context.Background(), sender, target, ip, timeout)
+}
+
+// WakeAndWaitContext wakes the device and waits like WakeAndWait. It stops
+// waiting when the context is done.
+func WakeAndWaitContext(ctx context.Context, sender Sender, target Target, ip string, timeout time.Duration) (time.Duration, error) {
+	return WakeAndWaitCheck(ctx, sender, target, Check{Address: ip}, timeout)
+}
+
+// WakeAndWaitCheck wakes the device and waits like WakeAndWaitContext, but
+// checks if the device is online with the probe of the check instead of
+// pinging it.
+func WakeAndWaitCheck(ctx context.Context, sender Sender, target Target, check Check, timeout time.Duration) (time.Duration, error) {
+	if err := WakeDeviceContext(ctx, sender, target); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	delay := minPollDelay
+
+	for {
+		if ProbeCheck(ctx, check).State == StateOnline {
+			return time.Since(start), nil
+		}
+
+		// Give up once the timeout has passed
+		if time.Since(start)+delay > timeout {
+			return time.Since(start), fmt.Errorf("%w within %s", ErrTimeout, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return time.Since(start), ctx.Err()
+		case <-time.After(delay):
+		}
+
+		// Back off so slow booting devices aren't flooded with pings
+		delay = min(delay*2, maxPollDelay)
+	}
+}
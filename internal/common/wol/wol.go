@@ -2,155 +2,298 @@ package wol
 
 import (
 	"bytes"
-	"encoding/binary"
+	"context"
 	"fmt"
 	"net"
 	"regexp"
-	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+)
+
+const (
+	DefaultBroadcastAddress = "255.255.255.255"      // Used when a target has no broadcast address
+	DefaultPort             = 9                      // Used when a target has no port
+	DefaultInterval         = 100 * time.Millisecond // Used between repeated packets when a target has no interval
+	EtherType               = 0x0842                 // EtherType of Wake-on-LAN Ethernet frames
+)
 
-	probing "github.com/prometheus-community/pro-bing"
+// Transports that can be used to send a magic packet.
+const (
+	TransportUDP      = "udp"      // UDP broadcast, the default
+	TransportEthernet = "ethernet" // Raw Ethernet frame with EtherType 0x0842
+	TransportIPv6     = "ipv6"     // UDP to the IPv6 all-nodes multicast address
 )
 
+// Transports lists every supported transport.
+var Transports = []string{TransportUDP, TransportEthernet, TransportIPv6}
+
 var (
 	delims = ":-"
-	reMAC  = regexp.MustCompile(`^([0-9a-fA-F]{2}[` + delims + `]){5}([0-9a-fA-F]{2})$`)
+	reMAC  = regexp.MustCompile(`^([0-9a-fA-F]{2}[` + delims + `]){5}(([0-9a-fA-F]{2}[` + delims + `]){2})?([0-9a-fA-F]{2})$`)
 )
 
-// MACAddress represents a 6 byte network mac address.
-type MACAddress [6]byte
-
 // MagicPacket is constituted of 6 bytes of 0xFF followed by 16-groups of the
-// destination MAC address.
+// destination hardware address, optionally followed by a 4 or 6 byte SecureOn
+// password. The hardware address is either a 6 byte MAC-48 or an 8 byte
+// EUI-64 address.
 type MagicPacket struct {
-	header  [6]byte
-	payload [16]MACAddress
+	header   [6]byte
+	hwAddr   net.HardwareAddr
+	password []byte
 }
 
-// New returns a magic packet based on a mac address string.
-func New(mac string) (*MagicPacket, error) {
+// Target holds everything needed to wake a single device.
+type Target struct {
+	ID         string // Optional ID of the device, used to report results
+	MacAddress string // MAC address of the device
+	Password   string // Optional SecureOn password
+
+	BroadcastAddress string // Optional broadcast address, defaults to 255.255.255.255
+	Port             int    // Optional UDP port, defaults to 9
+	Interface        string // Optional interface name or local address to send from
+	Transport        string // Optional transport, defaults to udp
+
+	Repeat   int           // Optional number of packets to send, defaults to 1
+	Interval time.Duration // Optional delay between repeated packets, defaults to 100ms
+
+	Relay       string // Optional address of a relay that sends the packet on its network
+	RelaySecret string // Secret shared with the relay
+}
+
+// Packets returns the number of packets sent to the target.
+func (t Target) Packets() int {
+	return max(t.Repeat, 1)
+}
+
+// Address returns the broadcast address and port the packet is sent to,
+// 255.255.255.255:9 when the target doesn't set them.
+func (t Target) Address() string {
+	broadcast := t.BroadcastAddress
+	if broadcast == "" {
+		broadcast = DefaultBroadcastAddress
+	}
+
+	port := t.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+
+	return net.JoinHostPort(broadcast, strconv.Itoa(port))
+}
+
+// New returns a magic packet based on a mac address string and an optional
+// SecureOn password.
+func New(mac string, password ...string) (*MagicPacket, error) {
 	var packet MagicPacket
-	var macAddr MACAddress
 
 	hwAddr, err := net.ParseMAC(mac)
 	if err != nil {
 		return nil, err
 	}
 
-	// We only support 6 byte MAC addresses since it is much harder to use the
-	// binary.Write(...) interface when the size of the MagicPacket is dynamic.
+	// We support 6 byte MAC-48 and 8 byte EUI-64 addresses written with
+	// colons or dashes.
 	if !reMAC.MatchString(mac) {
-		return nil, fmt.Errorf("%s is not a IEEE 802 MAC-48 address", mac)
-	}
-
-	// Copy bytes from the returned HardwareAddr -> a fixed size MACAddress.
-	for idx := range macAddr {
-		macAddr[idx] = hwAddr[idx]
+		return nil, fmt.Errorf("%s is not a IEEE 802 MAC-48 or EUI-64 address", mac)
 	}
+	packet.hwAddr = hwAddr
 
 	// Setup the header which is 6 repetitions of 0xFF.
 	for idx := range packet.header {
 		packet.header[idx] = 0xFF
 	}
 
-	// Setup the payload which is 16 repetitions of the MAC addr.
-	for idx := range packet.payload {
-		packet.payload[idx] = macAddr
+	// Setup the SecureOn password if one was given.
+	if len(password) > 0 {
+		packet.password, err = ParsePassword(password[0])
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &packet, nil
 }
 
-// Marshal serializes the magic packet structure into a 102 byte slice.
+// ParsePassword parses a SecureOn password. The password is either 6 bytes
+// written like a MAC address (00:11:22:33:44:55) or 4 bytes written like an
+// IPv4 address (192.168.1.1). An empty string means no password.
+func ParsePassword(password string) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+
+	// Dotted decimal notation is always 4 bytes
+	if strings.Contains(password, ".") {
+		ip := net.ParseIP(password).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("%s is not a valid SecureOn password", password)
+		}
+		return []byte(ip), nil
+	}
+
+	// Hex notation is 4 or 6 bytes separated by ':' or '-'
+	parts := strings.FieldsFunc(password, func(r rune) bool {
+		return strings.ContainsRune(delims, r)
+	})
+	if len(parts) != 4 && len(parts) != 6 {
+		return nil, fmt.Errorf("%s is not a valid SecureOn password", password)
+	}
+
+	pw := make([]byte, len(parts))
+	for idx, part := range parts {
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil || len(part) != 2 {
+			return nil, fmt.Errorf("%s is not a valid SecureOn password", password)
+		}
+		pw[idx] = byte(b)
+	}
+
+	return pw, nil
+}
+
+// Marshal serializes the magic packet structure into a 102 byte slice for a
+// MAC-48 address or a 134 byte slice for an EUI-64 address, followed by the
+// 4 or 6 byte SecureOn password if one is set.
 func (mp *MagicPacket) Marshal() ([]byte, error) {
 	var buf bytes.Buffer
-	if err := binary.Write(&buf, binary.BigEndian, mp); err != nil {
-		return nil, err
+	buf.Write(mp.header[:])
+
+	// The payload is 16 repetitions of the hardware address
+	for range 16 {
+		buf.Write(mp.hwAddr)
 	}
 
+	// The password is appended after the 16 repetitions
+	buf.Write(mp.password)
+
 	return buf.Bytes(), nil
 }
 
-// Wake the device
-func WakeDevice(mac string) error {
+// Wake the device by sending the magic packet with the sender
+func WakeDevice(sender Sender, target Target) error {
+	return WakeDeviceContext(context.Background(), sender, target)
+}
+
+// WakeDeviceContext wakes the device like WakeDevice. It stops sending
+// packets when the context is done.
+func WakeDeviceContext(ctx context.Context, sender Sender, target Target) error {
 	// Create a new magic packet
-	packet, err := New(mac)
+	packet, err := New(target.MacAddress, target.Password)
 
 	// Check for errors
 	if err != nil {
 		return err
 	}
 
-	// Open a UDP connection to the broadcast address
-	conn, err := net.Dial("udp", "255.255.255.255:9")
-
-	// Check for errors
+	// Marshal the magic packet
+	packetBytes, err := packet.Marshal()
 	if err != nil {
 		return err
 	}
 
-	// Close the connection when the function returns
-	defer conn.Close()
+	// A relay repeats the packets itself
+	packets := target.Packets()
+	if target.Relay != "" {
+		packets = 1
+	}
 
-	// Marshal the magic packet
-	packetBytes, err := packet.Marshal()
-	if err != nil {
-		return err
+	interval := target.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
 	}
 
-	// Send the magic packet to the broadcast address
-	_, err = conn.Write(packetBytes)
-	if err != nil {
+	// Don't send anything when the context is already done
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	// Return nil if everything was successful
-	return nil
-}
+	// Send the magic packet as many times as requested since a single
+	// broadcast is easily lost on busy networks
+	for i := 0; i < packets; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
 
-// WakeGroup sends a Wake-on-LAN packet to each MAC address in the list.
-func WakeGroup(macAddresses []string) error {
-	for _, mac := range macAddresses {
-		err := WakeDevice(mac)
-		if err != nil {
+		if err := sender.Send(ctx, target, packetBytes); err != nil {
 			return err
 		}
 	}
+
 	return nil
 }
 
-func checkOS() string {
-	return runtime.GOOS
-}
+// sendUDP sends the magic packet to the broadcast address of the target.
+func sendUDP(ctx context.Context, target Target, packet []byte) error {
+	// Open a broadcast UDP socket to the broadcast address
+	conn, err := dial(ctx, target)
 
-// Ping the device
-func IsOnline(ip string) bool {
+	// Check for errors
+	if err != nil {
+		return err
+	}
 
-	// Get OS
-	userOS := checkOS()
+	// Close the connection when the function returns
+	defer conn.Close()
 
-	pinger, err := probing.NewPinger(ip)
-	if err != nil {
-		panic(err)
+	// Don't block past the deadline of the context
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
 	}
 
-	// Check if the user is using Windows
-	// If the user is using Windows, use then set the pinger to use the Windows implementation
-	// If the user is not using Windows, use the default implementation
-	if userOS == "windows" {
-		pinger.SetPrivileged(true)
+	// Send the magic packet to the broadcast address
+	_, err = conn.Write(packet)
+	if err != nil {
+		return err
 	}
 
-	pinger.Count = 1
-	pinger.Timeout = time.Second * 1 // 1 seconds
+	// Return nil if everything was successful
+	return nil
+}
 
-	pinger.Run() // blocks until finished
+// Result is the outcome of waking a single device of a group.
+type Result struct {
+	DeviceID string
+	Err      error
+}
 
-	stats := pinger.Statistics() // get send/receive/rtt stats
+// WakeGroup sends a Wake-on-LAN packet to every target in the list at the
+// same time. It returns a result for each target in the order of the list, so
+// one bad target doesn't stop the others from waking.
+func WakeGroup(sender Sender, targets []Target) []Result {
+	return WakeGroupContext(context.Background(), sender, targets)
+}
 
-	if stats.PacketsRecv > 0 {
-		return true
-	} else {
-		return false
+// WakeGroupContext wakes the group like WakeGroup. Targets that haven't been
+// woken when the context is done report the error of the context.
+func WakeGroupContext(ctx context.Context, sender Sender, targets []Target) []Result {
+	results := make([]Result, len(targets))
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = Result{DeviceID: target.ID, Err: WakeDeviceContext(ctx, sender, target)}
+		}()
 	}
+	wg.Wait()
+
+	return results
+}
+
+// Ping the device
+func IsOnline(ip string) bool {
+	return IsOnlineContext(context.Background(), ip)
+}
+
+// IsOnlineContext pings the device like IsOnline. It gives up and reports the
+// device as offline when the context is done.
+func IsOnlineContext(ctx context.Context, ip string) bool {
+	return Probe(ctx, ip).State == StateOnline
 }
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 	"wakey/internal/common/wol"
+
+	"github.com/google/uuid"
 )
 
 // Config struct for the config file.
@@ -16,6 +21,106 @@ type Device struct {
 	MacAddress  string `json:"MacAddress"`
 	IPAddress   string `json:"IPAddress"`
 	State       string `json:"State"`
+	RTT         string `json:"RTT,omitempty"`        // round trip time of the last probe
+	ResolvedIP  string `json:"ResolvedIP,omitempty"` // address the last probe found the device on, if it differs from IPAddress
+	SecureOn    string `json:"SecureOn,omitempty"`   // optional SecureOn password
+
+	BroadcastAddress string `json:"BroadcastAddress,omitempty"` // optional broadcast address
+	Port             int    `json:"Port,omitempty"`             // optional UDP port
+	Interface        string `json:"Interface,omitempty"`        // optional interface or local address to send from
+	Transport        string `json:"Transport,omitempty"`        // optional transport, udp, ipv6 or ethernet
+	Repeat           int    `json:"Repeat,omitempty"`           // optional number of packets to send
+	Interval         int    `json:"Interval,omitempty"`         // optional milliseconds between packets
+	Relay            string `json:"Relay,omitempty"`            // optional name of the relay to wake through
+	TrackIP          bool   `json:"TrackIP,omitempty"`          // optional, update IPAddress from the neighbor table by MAC address
+
+	ProbeType    string `json:"ProbeType,omitempty"`    // optional probe type, icmp, tcp or http
+	ProbePort    int    `json:"ProbePort,omitempty"`    // optional port for the tcp and http probes
+	ProbeURL     string `json:"ProbeURL,omitempty"`     // optional URL for the http probe
+	ProbeStatus  int    `json:"ProbeStatus,omitempty"`  // optional status code the http probe expects
+	ProbeTimeout int    `json:"ProbeTimeout,omitempty"` // optional milliseconds to wait for the probe
+}
+
+// MaxDescription is the longest description of a device, in bytes.
+const MaxDescription = 64
+
+// NewDevice returns a new device with a generated ID. Every device that is
+// added to the config is created here, by the form or by an import.
+func NewDevice(name, description, macAddress, ipAddress string) Device {
+	return Device{
+		ID:          uuid.NewString(),
+		DeviceName:  name,
+		Description: description,
+		MacAddress:  macAddress,
+		IPAddress:   ipAddress,
+		State:       "Offline",
+	}
+}
+
+// SetProbeResult records the result of a probe as the State and RTT of the
+// device.
+func (d *Device) SetProbeResult(result wol.ProbeResult) {
+	d.State = result.String()
+	d.RTT = ""
+	d.ResolvedIP = ""
+
+	if result.State == wol.StateOnline && result.RTT > 0 {
+		d.RTT = result.RTT.Round(10 * time.Microsecond).String()
+	}
+
+	if result.IP != nil && result.IP.String() != d.IPAddress {
+		d.ResolvedIP = result.IP.String()
+	}
+}
+
+// Target returns the Wake-on-LAN target for the device. Settings that are not
+// set on the device fall back to the global settings.
+func (d Device) Target(settings Settings) wol.Target {
+	target := wol.Target{
+		ID:         d.ID,
+		MacAddress: d.MacAddress,
+		Password:   d.SecureOn,
+
+		BroadcastAddress: d.BroadcastAddress,
+		Port:             d.Port,
+		Interface:        d.Interface,
+		Transport:        d.Transport,
+		Repeat:           d.Repeat,
+	}
+
+	if target.Interface == "" {
+		target.Interface = settings.Interface
+	}
+
+	if target.Repeat == 0 {
+		target.Repeat = settings.Repeat
+	}
+
+	interval := d.Interval
+	if interval == 0 {
+		interval = settings.Interval
+	}
+	target.Interval = time.Duration(interval) * time.Millisecond
+
+	if relay, ok := settings.GetRelay(d.Relay); ok {
+		target.Relay = relay.Address
+		target.RelaySecret = relay.Secret
+	}
+
+	return target
+}
+
+// Check returns the check that finds out if the device is online.
+func (d Device) Check() wol.Check {
+	return wol.Check{
+		Type:    d.ProbeType,
+		Address: d.IPAddress,
+		MAC:     d.MacAddress,
+		Port:    d.ProbePort,
+		URL:     d.ProbeURL,
+		Status:  d.ProbeStatus,
+		Timeout: time.Duration(d.ProbeTimeout) * time.Millisecond,
+	}
 }
 
 type Group struct {
@@ -24,10 +129,88 @@ type Group struct {
 	Devices   []string `json:"Devices"` // contains IDs of devices
 }
 
+// DefaultWakeTimeout is how long to wait for a device to come online after
+// waking it when no timeout is set.
+const DefaultWakeTimeout = 2 * time.Minute
+
+// DefaultPollInterval is how often the state of the devices is refreshed in
+// the background when no poll interval is set.
+const DefaultPollInterval = 30 * time.Second
+
+// Settings struct for the global settings in the config file.
+type Settings struct {
+	Interface    string  `json:"Interface,omitempty"`    // interface or local address to send from
+	WakeTimeout  int     `json:"WakeTimeout,omitempty"`  // seconds to wait for a device to come online
+	Repeat       int     `json:"Repeat,omitempty"`       // number of packets to send
+	Interval     int     `json:"Interval,omitempty"`     // milliseconds between packets
+	PollInterval int     `json:"PollInterval,omitempty"` // seconds between background refreshes, negative turns polling off
+	OUIFile      string  `json:"OUIFile,omitempty"`      // IEEE oui.txt with the vendors of MAC addresses
+	Relays       []Relay `json:"Relays,omitempty"`       // relays that wake devices on other networks
+}
+
+// Relay struct for a relay that wakes devices on its own network.
+type Relay struct {
+	Name    string `json:"Name"`
+	Address string `json:"Address"` // host or host:port of the relay
+	Secret  string `json:"Secret"`  // secret shared with the relay
+}
+
+// GetRelay returns the relay with the given name.
+func (s Settings) GetRelay(name string) (Relay, bool) {
+	if name == "" {
+		return Relay{}, false
+	}
+	for _, relay := range s.Relays {
+		if relay.Name == name {
+			return relay, true
+		}
+	}
+	return Relay{}, false
+}
+
+// GetWakeTimeout returns the wake timeout or the default if it isn't set.
+func (s Settings) GetWakeTimeout() time.Duration {
+	if s.WakeTimeout <= 0 {
+		return DefaultWakeTimeout
+	}
+	return time.Duration(s.WakeTimeout) * time.Second
+}
+
+// GetPollInterval returns the poll interval, the default if it isn't set, or
+// 0 if polling is turned off.
+func (s Settings) GetPollInterval() time.Duration {
+	switch {
+	case s.PollInterval < 0:
+		return 0
+	case s.PollInterval == 0:
+		return DefaultPollInterval
+	}
+	return time.Duration(s.PollInterval) * time.Second
+}
+
+// LoadOUIFile loads the vendors of MAC addresses from the OUI file, if it is
+// set, in addition to the vendors that are built in.
+func (s Settings) LoadOUIFile() error {
+	if s.OUIFile == "" {
+		return nil
+	}
+
+	path := s.OUIFile
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		path = filepath.Join(HomeDir, rest)
+	}
+
+	if err := wol.LoadOUIFile(path); err != nil {
+		return fmt.Errorf("error loading OUI file: %v", err)
+	}
+	return nil
+}
+
 // Config struct for the config file.
 type Config struct {
-	Devices []Device `json:"devices"`
-	Groups  []Group  `json:"groups"`
+	Devices  []Device `json:"devices"`
+	Groups   []Group  `json:"groups"`
+	Settings Settings `json:"settings"`
 }
 
 var (
@@ -108,13 +291,25 @@ func ReadConfig() Config {
 
 // Write the config to the config file.
 func WriteConfig(config Config) {
+	// Write the config and check if we got an error
+	if err := saveConfig(config); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Print a message to the user
+	fmt.Println("Config file updated at", ConfigPath)
+}
+
+// saveConfig writes the config to the config file without printing, so it
+// can be used while the TUI is running.
+func saveConfig(config Config) error {
 	// Marshal the config to JSON
 	data, err := json.MarshalIndent(config, "", "  ")
 
 	// Check if we got an error
 	if err != nil {
-		fmt.Println("Error marshalling config:", err)
-		return
+		return fmt.Errorf("Error marshalling config: %v", err)
 	}
 
 	// Write the config to the file
@@ -122,39 +317,37 @@ func WriteConfig(config Config) {
 
 	// Check if we got an error
 	if err != nil {
-		fmt.Println("Error writing config file:", err)
-		return
+		return fmt.Errorf("Error writing config file: %v", err)
 	}
 
-	// Print a message to the user
-	fmt.Println("Config file updated at", ConfigPath)
+	return nil
 }
 
 // Update the State of the devices
 func GetUpdateState() Config {
-	// Get the devices
-	cfg := ReadConfig()
-	devices := cfg.Devices
-	groups := cfg.Groups
-
-	// Loop through the devices
-	for i, device := range devices {
-		// Get the State of the device
-		isOnline := wol.IsOnline(device.IPAddress)
-
-		// Update the State of the device
-		if isOnline {
-			devices[i].State = "Online"
-		} else {
-			devices[i].State = "Offline"
-		}
+	return GetUpdateStateContext(context.Background())
+}
+
+// GetUpdateStateContext updates the State of the devices like GetUpdateState.
+// The devices are probed concurrently. When the context is done the remaining
+// devices aren't probed and the config file is left as it is.
+func GetUpdateStateContext(ctx context.Context) Config {
+	// Probe the devices and wait for all results
+	for range StreamUpdateState(ctx) {
 	}
 
-	// Write the updated config file
-	WriteConfig(Config{Devices: devices, Groups: groups})
+	// Return the updated config file
+	return ReadConfig()
+}
 
-	// Return the config file
-	return Config{Devices: devices, Groups: groups}
+// GetDevice returns the device with the given ID.
+func (c Config) GetDevice(id string) (Device, bool) {
+	for _, device := range c.Devices {
+		if device.ID == id {
+			return device, true
+		}
+	}
+	return Device{}, false
 }
 
 /*
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"time"
+	"wakey/internal/common/wol"
+)
+
+// ProbeWorkers is the maximum number of devices that are probed at the same
+// time during a refresh.
+const ProbeWorkers = 64
+
+// TrackSweepInterval is the minimum time between two sweeps of the local
+// networks for devices that track their IP address. Sleeping devices never
+// show up in the neighbor table, so without it every refresh would sweep.
+const TrackSweepInterval = 5 * time.Minute
+
+// TrackSweep sweeps the local networks for devices that track their IP
+// address. It can be replaced, e.g. by tests that must not send packets.
+var TrackSweep = wol.Sweep
+
+var (
+	sweepMu   sync.Mutex
+	lastSweep time.Time // when the local networks were last swept for tracked devices
+)
+
+// StateUpdate is the result of probing a single device during a refresh.
+type StateUpdate struct {
+	DeviceID   string
+	Result     wol.ProbeResult
+	IPAddress  string // new IP address of a device tracked by MAC address, empty when it didn't change
+	PreviousIP string // IP address the device had before it changed
+}
+
+// Apply records the update on the device.
+func (u StateUpdate) Apply(d *Device) {
+	if u.IPAddress != "" {
+		d.IPAddress = u.IPAddress
+	}
+	d.SetProbeResult(u.Result)
+}
+
+// StreamUpdateState probes every device in the config file concurrently and
+// sends each result on the returned channel as soon as it arrives. The channel
+// is buffered, so results that aren't read never block the refresh. Once every
+// device has been probed the states are written to the config file and the
+// channel is closed. When the context is done the remaining devices aren't
+// probed and the config file is left as it is.
+func StreamUpdateState(ctx context.Context) <-chan StateUpdate {
+	devices := ReadConfig().Devices
+
+	// Buffer every result so a slow reader never holds up the refresh
+	updates := make(chan StateUpdate, len(devices))
+
+	go func() {
+		defer close(updates)
+
+		// Find devices that got a new IP address before probing them
+		previous := trackDevices(ctx, devices)
+
+		results := make(map[string]StateUpdate)
+		for update := range probeDevices(ctx, devices) {
+			if previousIP, ok := previous[update.DeviceID]; ok {
+				device, _ := Config{Devices: devices}.GetDevice(update.DeviceID)
+				update.IPAddress = device.IPAddress
+				update.PreviousIP = previousIP
+			}
+
+			results[update.DeviceID] = update
+			updates <- update
+		}
+
+		// Don't record the states of a cancelled refresh
+		if ctx.Err() != nil {
+			return
+		}
+
+		saveStates(results)
+	}()
+
+	return updates
+}
+
+// probeDevices probes the devices with at most ProbeWorkers probes at the same
+// time and sends every result on the returned channel, which is closed once
+// all probes have finished.
+func probeDevices(ctx context.Context, devices []Device) <-chan StateUpdate {
+	jobs := make(chan Device)
+	updates := make(chan StateUpdate)
+
+	var wg sync.WaitGroup
+	for range min(ProbeWorkers, len(devices)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for device := range jobs {
+				updates <- StateUpdate{DeviceID: device.ID, Result: wol.ProbeCheck(ctx, device.Check())}
+			}
+		}()
+	}
+
+	// Hand out the devices until all are probed or the refresh is cancelled
+	go func() {
+		defer close(jobs)
+		for _, device := range devices {
+			select {
+			case jobs <- device:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Close the results once every worker is done
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+
+	return updates
+}
+
+// saveStates records the updates in the config file. The config file is read
+// again so changes made during the refresh aren't lost.
+func saveStates(updates map[string]StateUpdate) {
+	cfg := ReadConfig()
+	for i, device := range cfg.Devices {
+		if update, ok := updates[device.ID]; ok {
+			update.Apply(&cfg.Devices[i])
+		}
+	}
+
+	saveConfig(cfg)
+}
+
+// trackDevices looks up the devices that track their IP address in the
+// neighbor table and updates the IP address of the devices that got a new
+// one. The local networks are swept first when a device isn't in the table,
+// at most once every TrackSweepInterval. It returns the previous IP address
+// of the updated devices by ID.
+func trackDevices(ctx context.Context, devices []Device) map[string]string {
+	// Host names are resolved when probing, so only IP addresses are tracked
+	var tracked []*Device
+	for i := range devices {
+		if devices[i].TrackIP && (devices[i].IPAddress == "" || net.ParseIP(devices[i].IPAddress) != nil) {
+			tracked = append(tracked, &devices[i])
+		}
+	}
+
+	if len(tracked) == 0 {
+		return nil
+	}
+
+	neighbors, _ := wol.Neighbors(ctx)
+
+	// Sweep once so devices that have been quiet show up in the table
+	for _, device := range tracked {
+		if _, ok := trackedIP(neighbors, *device); !ok {
+			if sweepDue() && TrackSweep(ctx) == nil {
+				neighbors, _ = wol.Neighbors(ctx)
+			}
+			break
+		}
+	}
+
+	previous := make(map[string]string)
+	for _, device := range tracked {
+		if ip, ok := trackedIP(neighbors, *device); ok && ip != device.IPAddress {
+			previous[device.ID] = device.IPAddress
+			device.IPAddress = ip
+		}
+	}
+
+	return previous
+}
+
+// sweepDue reports whether the local networks can be swept for tracked
+// devices, and records the sweep if they can.
+func sweepDue() bool {
+	sweepMu.Lock()
+	defer sweepMu.Unlock()
+
+	if time.Since(lastSweep) < TrackSweepInterval {
+		return false
+	}
+	lastSweep = time.Now()
+	return true
+}
+
+// trackedIP returns the IP address the device was recently seen on in the
+// neighbor table, preferring reachable entries over stale ones. The address
+// has the same family as the IP address of the device, and IPv4 is used when
+// the device has no valid IP address.
+func trackedIP(neighbors []wol.Neighbor, device Device) (string, bool) {
+	mac, err := net.ParseMAC(device.MacAddress)
+	if err != nil {
+		return "", false
+	}
+
+	current := net.ParseIP(device.IPAddress)
+	wantIPv4 := current == nil || current.To4() != nil
+
+	var found string
+	for _, neighbor := range neighbors {
+		if !bytes.Equal(neighbor.MAC, mac) || !neighbor.State.Recent() {
+			continue
+		}
+
+		// Link-local IPv6 addresses can't be used without a zone
+		if (neighbor.IP.To4() != nil) != wantIPv4 || neighbor.IP.IsLinkLocalUnicast() {
+			continue
+		}
+
+		if neighbor.State == wol.NeighborReachable {
+			return neighbor.IP.String(), true
+		}
+		if found == "" {
+			found = neighbor.IP.String()
+		}
+	}
+
+	return found, found != ""
+}
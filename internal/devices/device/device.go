@@ -2,8 +2,12 @@ package device
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"wakey/internal/common/refresh"
 	"wakey/internal/common/status"
 	"wakey/internal/common/style"
+	"wakey/internal/common/wol"
 	"wakey/internal/config"
 
 	"github.com/charmbracelet/bubbles/help"
@@ -11,7 +15,6 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/google/uuid"
 )
 
 var (
@@ -29,13 +32,15 @@ type Model struct {
 	keys          keyMap
 	help          help.Model
 	selectedRow   []string
+	device        config.Device // the device being edited
+	vendor        string        // vendor filled in as the description
 }
 
 // InitialModel returns the initial model for the Device component
 func InitialModel(previousModel tea.Model, selectedRow ...[]string) Model {
 	m := Model{
-		err:           make([]error, 4),           // Initialize the slice with length 4
-		inputs:        make([]textinput.Model, 4), // Initialize the slice with length 4
+		err:           make([]error, 18),           // Initialize the slice with length 18
+		inputs:        make([]textinput.Model, 18), // Initialize the slice with length 18
 		currentConfig: config.ReadConfig(),
 		keys:          keys,
 		help:          help.New(),
@@ -46,6 +51,9 @@ func InitialModel(previousModel tea.Model, selectedRow ...[]string) Model {
 	if len(selectedRow) > 0 {
 		// Set the selected row
 		m.selectedRow = selectedRow[0]
+
+		// Get the device so fields not shown in the table can be edited
+		m.device, _ = m.currentConfig.GetDevice(m.selectedRow[0])
 	}
 
 	// Create a new text input model for each input field
@@ -87,11 +95,116 @@ func InitialModel(previousModel tea.Model, selectedRow ...[]string) Model {
 			}
 		// IP address
 		case 3:
-			ti.Prompt = "IP Address    : "
-			ti.Placeholder = "0.0.0.0"
+			ti.Prompt = "Address       : "
+			ti.Placeholder = "0.0.0.0, nas.lan or gaming-pc.local"
 
 			if selectedRow != nil {
-				ti.SetValue(selectedRow[0][4])
+				ti.SetValue(m.device.IPAddress)
+			}
+		// SecureOn password
+		case 4:
+			ti.Prompt = "SecureOn      : "
+			ti.Placeholder = "Optional, 00:00:00:00:00:00 or 0.0.0.0"
+			ti.SetValue(m.device.SecureOn)
+		// Broadcast address
+		case 5:
+			ti.Prompt = "Broadcast     : "
+			ti.Placeholder = wol.DefaultBroadcastAddress
+			ti.SetValue(m.device.BroadcastAddress)
+		// UDP port
+		case 6:
+			ti.Prompt = "Port          : "
+			ti.Placeholder = strconv.Itoa(wol.DefaultPort)
+
+			if m.device.Port != 0 {
+				ti.SetValue(strconv.Itoa(m.device.Port))
+			}
+		// Source interface
+		case 7:
+			ti.Prompt = "Interface     : "
+			ti.Placeholder = "Optional, e.g. eth0 or 192.168.1.10"
+			ti.SetValue(m.device.Interface)
+		// Transport
+		case 8:
+			ti.Prompt = "Transport     : "
+			ti.Placeholder = strings.Join(wol.Transports, " or ")
+			ti.ShowSuggestions = true
+			ti.SetSuggestions(wol.Transports)
+			ti.SetValue(m.device.Transport)
+		// Number of packets
+		case 9:
+			ti.Prompt = "Repeat        : "
+			ti.Placeholder = "Optional, number of packets to send"
+
+			if m.device.Repeat != 0 {
+				ti.SetValue(strconv.Itoa(m.device.Repeat))
+			}
+		// Delay between packets
+		case 10:
+			ti.Prompt = "Interval (ms) : "
+			ti.Placeholder = strconv.Itoa(int(wol.DefaultInterval.Milliseconds()))
+
+			if m.device.Interval != 0 {
+				ti.SetValue(strconv.Itoa(m.device.Interval))
+			}
+		// Relay
+		case 11:
+			var relayNames []string
+			for _, relay := range m.currentConfig.Settings.Relays {
+				relayNames = append(relayNames, relay.Name)
+			}
+
+			ti.Prompt = "Relay         : "
+			ti.Placeholder = "Optional, name of a relay in the settings"
+			ti.ShowSuggestions = true
+			ti.SetSuggestions(relayNames)
+			ti.SetValue(m.device.Relay)
+		// Probe type
+		case 12:
+			ti.Prompt = "Probe         : "
+			ti.Placeholder = strings.Join(wol.ProbeTypes, ", ") + " (default " + wol.ProbeICMP + ")"
+			ti.ShowSuggestions = true
+			ti.SetSuggestions(wol.ProbeTypes)
+			ti.SetValue(m.device.ProbeType)
+		// Port of the tcp and http probes
+		case 13:
+			ti.Prompt = "Probe Port    : "
+			ti.Placeholder = "Required for tcp, e.g. 22, 3389 or 445"
+
+			if m.device.ProbePort != 0 {
+				ti.SetValue(strconv.Itoa(m.device.ProbePort))
+			}
+		// URL of the http probe
+		case 14:
+			ti.CharLimit = 256
+			ti.Prompt = "Probe URL     : "
+			ti.Placeholder = "Optional, e.g. https://192.168.1.10/health"
+			ti.SetValue(m.device.ProbeURL)
+		// Status code the http probe expects
+		case 15:
+			ti.Prompt = "Probe Status  : "
+			ti.Placeholder = strconv.Itoa(wol.DefaultHTTPStatus)
+
+			if m.device.ProbeStatus != 0 {
+				ti.SetValue(strconv.Itoa(m.device.ProbeStatus))
+			}
+		// Timeout of the probe
+		case 16:
+			ti.Prompt = "Timeout (ms)  : "
+			ti.Placeholder = strconv.Itoa(int(wol.DefaultProbeTimeout.Milliseconds()))
+
+			if m.device.ProbeTimeout != 0 {
+				ti.SetValue(strconv.Itoa(m.device.ProbeTimeout))
+			}
+		// Track the IP address by MAC address
+		case 17:
+			ti.Prompt = "Track IP      : "
+			ti.Placeholder = "yes or no (default no)"
+			ti.ShowSuggestions = true
+			ti.SetSuggestions([]string{"yes", "no"})
+
+			if m.device.TrackIP {
+				ti.SetValue("yes")
 			}
 		}
 
@@ -115,7 +228,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch {
 		// Return to the list
 		case key.Matches(msg, m.keys.Quit):
-			return m.previousModel, nil
+			return m.previousModel, refresh.Resume(m.previousModel)
 
 		// Toggle help
 		case key.Matches(msg, m.keys.Help):
@@ -131,6 +244,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.err[1] = m.descriptionValidator(m.inputs[1].Value())
 				m.err[2] = m.macAddressValidator(m.inputs[2].Value())
 				m.err[3] = m.ipAddressValidator(m.inputs[3].Value())
+				m.err[4] = m.secureOnValidator(m.inputs[4].Value())
+				m.err[5] = m.broadcastAddressValidator(m.inputs[5].Value())
+				m.err[6] = m.portValidator(m.inputs[6].Value())
+				m.err[7] = m.interfaceValidator(m.inputs[7].Value())
+				m.err[8] = m.transportValidator(m.inputs[8].Value())
+				m.err[9] = m.repeatValidator(m.inputs[9].Value())
+				m.err[10] = m.intervalValidator(m.inputs[10].Value())
+				m.err[11] = m.relayValidator(m.inputs[11].Value())
+				m.err[12] = m.probeTypeValidator(m.inputs[12].Value())
+				m.err[13] = m.probePortValidator(m.inputs[13].Value())
+				m.err[14] = m.probeURLValidator(m.inputs[14].Value())
+				m.err[15] = m.probeStatusValidator(m.inputs[15].Value())
+				m.err[16] = m.probeTimeoutValidator(m.inputs[16].Value())
+				m.err[17] = m.trackIPValidator(m.inputs[17].Value())
 
 				if m.focusIndex == len(m.inputs) {
 					// Handle form submission
@@ -154,53 +281,101 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						return m, nil
 					}
 
+					if !m.validateInput(4, m.secureOnValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(5, m.broadcastAddressValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(6, m.portValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(7, m.interfaceValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(8, m.transportValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(9, m.repeatValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(10, m.intervalValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(11, m.relayValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(12, m.probeTypeValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(13, m.probePortValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(14, m.probeURLValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(15, m.probeStatusValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(16, m.probeTimeoutValidator) {
+						return m, nil
+					}
+
+					if !m.validateInput(17, m.trackIPValidator) {
+						return m, nil
+					}
+
+					// Read the config again, the states are refreshed in the
+					// background while the form is open
+					currentConfig := config.ReadConfig()
+
 					// Check if we are editing an existing device
 					if m.selectedRow != nil {
 						// Get the selected device
 						selected := m.selectedRow
 
 						// Update the device in the config
-						for i, device := range m.currentConfig.Devices {
+						for i, device := range currentConfig.Devices {
 							if device.ID == selected[0] {
-								m.currentConfig.Devices[i] = config.Device{
-									ID:          m.currentConfig.Devices[i].ID,
-									DeviceName:  m.inputs[0].Value(),
-									Description: m.inputs[1].Value(),
-									MacAddress:  m.inputs[2].Value(),
-									IPAddress:   m.inputs[3].Value(),
-									State:       m.currentConfig.Devices[i].State,
+								m.setDevice(&currentConfig.Devices[i])
+
+								// Keep an IP address that was tracked while the
+								// form was open, unless it was edited
+								if m.inputs[3].Value() == m.device.IPAddress {
+									currentConfig.Devices[i].IPAddress = device.IPAddress
 								}
 								break
 							}
 						}
 					} else {
+						// Create the new device from the inputs
+						device := config.NewDevice(m.inputs[0].Value(), m.inputs[1].Value(), m.inputs[2].Value(), m.inputs[3].Value())
+						m.setDevice(&device)
+
 						// Append the device to the config
-						updatedDevices := append(m.currentConfig.Devices, config.Device{
-							ID:          uuid.NewString(),
-							DeviceName:  m.inputs[0].Value(),
-							Description: m.inputs[1].Value(),
-							MacAddress:  m.inputs[2].Value(),
-							IPAddress:   m.inputs[3].Value(),
-							State:       "Offline",
-						})
-
-						// Create a new config with the updated devices
-						m.currentConfig = config.Config{
-							Devices: updatedDevices,
-							Groups:  m.currentConfig.Groups,
-						}
+						currentConfig.Devices = append(currentConfig.Devices, device)
 					}
 
 					// Write the the new version of the config to the file
-					config.WriteConfig(m.currentConfig)
+					config.WriteConfig(currentConfig)
 
 					// Set the status message
 					status.Message = fmt.Errorf("device [%s] (%s) added", m.inputs[0].Value(), m.inputs[2].Value())
 
 					// Return to the list and clear the screen
-					return m.previousModel, func() tea.Msg {
-						return tea.ClearScreen()
-					}
+					return m.previousModel, tea.Batch(tea.ClearScreen, refresh.Resume(m.previousModel))
 				}
 			}
 
@@ -241,9 +416,50 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Handle character input and blinking
 	cmd := m.updateInputs(msg)
 
+	// Fill in the vendor as the description while the MAC address is typed
+	if m.focusIndex == 2 {
+		m.fillVendor()
+	}
+
 	return m, cmd
 }
 
+// fillVendor sets the description to the vendor of the MAC address, unless
+// the description has been typed in
+func (m *Model) fillVendor() {
+	description := m.inputs[1].Value()
+	if description != "" && description != m.vendor {
+		return
+	}
+
+	m.inputs[1].SetValue(wol.Vendor(m.inputs[2].Value()))
+	m.vendor = m.inputs[1].Value()
+}
+
+// setDevice copies the values of the inputs to the device
+func (m Model) setDevice(device *config.Device) {
+	device.DeviceName = m.inputs[0].Value()
+	device.Description = m.inputs[1].Value()
+	device.MacAddress = m.inputs[2].Value()
+	device.IPAddress = m.inputs[3].Value()
+	device.SecureOn = m.inputs[4].Value()
+	device.BroadcastAddress = m.inputs[5].Value()
+
+	// The port has already been validated, an empty value results in 0
+	device.Port, _ = strconv.Atoi(m.inputs[6].Value())
+	device.Interface = m.inputs[7].Value()
+	device.Transport = m.inputs[8].Value()
+	device.Repeat, _ = strconv.Atoi(m.inputs[9].Value())
+	device.Interval, _ = strconv.Atoi(m.inputs[10].Value())
+	device.Relay = m.inputs[11].Value()
+	device.ProbeType = m.inputs[12].Value()
+	device.ProbePort, _ = strconv.Atoi(m.inputs[13].Value())
+	device.ProbeURL = m.inputs[14].Value()
+	device.ProbeStatus, _ = strconv.Atoi(m.inputs[15].Value())
+	device.ProbeTimeout, _ = strconv.Atoi(m.inputs[16].Value())
+	device.TrackIP = m.inputs[17].Value() == "yes"
+}
+
 // Define the DeleteDevicePopup function
 func DeleteDevicePopup(deviceName, macAddress string, m tea.Model) (tea.Model, tea.Cmd) {
 	// Create a popup message for confirmation
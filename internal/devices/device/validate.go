@@ -2,7 +2,14 @@ package device
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"wakey/internal/common/wol"
+	"wakey/internal/config"
 )
 
 func (m *Model) deviceNameValidator(value string) error {
@@ -22,8 +29,8 @@ func (m *Model) descriptionValidator(value string) error {
 	}
 
 	// Check max length
-	if len(value) > 64 {
-		return fmt.Errorf("description must be less than 64 characters")
+	if len(value) > config.MaxDescription {
+		return fmt.Errorf("description must be less than %d characters", config.MaxDescription)
 	}
 
 	m.err[1] = nil
@@ -31,8 +38,8 @@ func (m *Model) descriptionValidator(value string) error {
 }
 
 func (m *Model) macAddressValidator(value string) error {
-	// Regular expression to match valid MAC addresses
-	var macAddressRegex = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){5}[0-9A-Fa-f]{2}$`)
+	// Regular expression to match valid MAC-48 and EUI-64 addresses
+	var macAddressRegex = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){5}(([0-9A-Fa-f]{2}:){2})?[0-9A-Fa-f]{2}$`)
 
 	if !macAddressRegex.MatchString(value) {
 		return fmt.Errorf("invalid mac address")
@@ -42,23 +49,225 @@ func (m *Model) macAddressValidator(value string) error {
 	return nil
 }
 
-func (m *Model) ipAddressValidator(value string) error {
-	var a, b, c, d int
+// Regular expression to match host names like nas.lan or gaming-pc.local
+var hostnameRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.?$`)
+
+// isHostname reports whether the value is a host name. The last label can't
+// be numeric, so mistyped IP addresses like 192.168.1.300 are rejected.
+func isHostname(value string) bool {
+	if len(value) > 253 || !hostnameRegex.MatchString(value) {
+		return false
+	}
 
+	labels := strings.Split(strings.TrimSuffix(value, "."), ".")
+	_, err := strconv.Atoi(labels[len(labels)-1])
+	return err != nil
+}
+
+func (m *Model) ipAddressValidator(value string) error {
 	// Check if the value is empty
 	if value == "" {
-		return fmt.Errorf("ip address is required")
+		return fmt.Errorf("address is required")
 	}
 
-	// Check if the value is a valid IP address
-	if _, err := fmt.Sscanf(value, "%d.%d.%d.%d", &a, &b, &c, &d); err != nil {
-		return fmt.Errorf("invalid ip address")
+	// Check if the value is a valid IPv4 or IPv6 address or a host name,
+	// host names are resolved when the device is probed
+	if net.ParseIP(value) == nil && !isHostname(value) {
+		return fmt.Errorf("invalid ip address or host name")
 	}
 
 	m.err[3] = nil
 	return nil
 }
 
+func (m *Model) secureOnValidator(value string) error {
+	// The SecureOn password is optional
+	if _, err := wol.ParsePassword(value); err != nil {
+		return fmt.Errorf("invalid secureon password")
+	}
+
+	m.err[4] = nil
+	return nil
+}
+
+func (m *Model) broadcastAddressValidator(value string) error {
+	// The broadcast address is optional, IPv6 addresses are multicast groups
+	// and host names are resolved when the packet is sent, like the hosts of
+	// imported wakeonlan and wol files
+	if value != "" && net.ParseIP(value) == nil && !isHostname(value) {
+		return fmt.Errorf("invalid broadcast address or host name")
+	}
+
+	m.err[5] = nil
+	return nil
+}
+
+func (m *Model) portValidator(value string) error {
+	// The port is optional
+	if value == "" {
+		m.err[6] = nil
+		return nil
+	}
+
+	// Check if the value is a valid port number
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+
+	m.err[6] = nil
+	return nil
+}
+
+func (m *Model) interfaceValidator(value string) error {
+	// The interface is optional and can also be a local IP address
+	if value == "" || net.ParseIP(value) != nil {
+		m.err[7] = nil
+		return nil
+	}
+
+	// Check if the interface exists on this machine
+	if _, err := net.InterfaceByName(value); err != nil {
+		return fmt.Errorf("interface %s not found", value)
+	}
+
+	m.err[7] = nil
+	return nil
+}
+
+func (m *Model) transportValidator(value string) error {
+	// The transport is optional
+	if value != "" && !slices.Contains(wol.Transports, value) {
+		return fmt.Errorf("transport must be one of %s", strings.Join(wol.Transports, ", "))
+	}
+
+	m.err[8] = nil
+	return nil
+}
+
+func (m *Model) repeatValidator(value string) error {
+	// The repeat count is optional
+	if value == "" {
+		m.err[9] = nil
+		return nil
+	}
+
+	if repeat, err := strconv.Atoi(value); err != nil || repeat < 1 || repeat > 100 {
+		return fmt.Errorf("repeat must be between 1 and 100")
+	}
+
+	m.err[9] = nil
+	return nil
+}
+
+func (m *Model) intervalValidator(value string) error {
+	// The interval is optional
+	if value == "" {
+		m.err[10] = nil
+		return nil
+	}
+
+	if interval, err := strconv.Atoi(value); err != nil || interval < 1 || interval > 10000 {
+		return fmt.Errorf("interval must be between 1 and 10000 ms")
+	}
+
+	m.err[10] = nil
+	return nil
+}
+
+func (m *Model) relayValidator(value string) error {
+	// The relay is optional but has to exist in the settings
+	if _, ok := m.currentConfig.Settings.GetRelay(value); value != "" && !ok {
+		return fmt.Errorf("relay '%s' does not exist", value)
+	}
+
+	m.err[11] = nil
+	return nil
+}
+
+func (m *Model) probeTypeValidator(value string) error {
+	// The probe type is optional
+	if value != "" && !slices.Contains(wol.ProbeTypes, value) {
+		return fmt.Errorf("probe must be one of %s", strings.Join(wol.ProbeTypes, ", "))
+	}
+
+	m.err[12] = nil
+	return nil
+}
+
+func (m *Model) probePortValidator(value string) error {
+	// The port is only required by the tcp probe
+	if value == "" {
+		if m.inputs[12].Value() == wol.ProbeTCP {
+			return fmt.Errorf("probe port is required for the tcp probe")
+		}
+
+		m.err[13] = nil
+		return nil
+	}
+
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("probe port must be between 1 and 65535")
+	}
+
+	m.err[13] = nil
+	return nil
+}
+
+func (m *Model) probeURLValidator(value string) error {
+	// The URL is optional, the http probe falls back to the IP address
+	if value == "" {
+		m.err[14] = nil
+		return nil
+	}
+
+	if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("probe url must be an http or https url")
+	}
+
+	m.err[14] = nil
+	return nil
+}
+
+func (m *Model) probeStatusValidator(value string) error {
+	// The expected status code is optional
+	if value == "" {
+		m.err[15] = nil
+		return nil
+	}
+
+	if code, err := strconv.Atoi(value); err != nil || code < 100 || code > 599 {
+		return fmt.Errorf("probe status must be between 100 and 599")
+	}
+
+	m.err[15] = nil
+	return nil
+}
+
+func (m *Model) probeTimeoutValidator(value string) error {
+	// The timeout is optional
+	if value == "" {
+		m.err[16] = nil
+		return nil
+	}
+
+	if timeout, err := strconv.Atoi(value); err != nil || timeout < 1 || timeout > 60000 {
+		return fmt.Errorf("timeout must be between 1 and 60000 ms")
+	}
+
+	m.err[16] = nil
+	return nil
+}
+
+func (m *Model) trackIPValidator(value string) error {
+	// Tracking the IP address is optional
+	if value != "" && value != "yes" && value != "no" {
+		return fmt.Errorf("track ip must be yes or no")
+	}
+
+	m.err[17] = nil
+	return nil
+}
+
 func (m *Model) validateInput(index int, validator func(string) error) bool {
 	if err := validator(m.inputs[index].Value()); err != nil {
 		m.err[index] = err
@@ -1,15 +1,20 @@
 package devices
 
 import (
+	"context"
 	"fmt"
 	"strconv"
-	"wakey/internal/common"
+	"sync"
+	"time"
 	"wakey/internal/common/popup"
+	"wakey/internal/common/refresh"
 	"wakey/internal/common/status"
 	"wakey/internal/common/style"
 	"wakey/internal/common/wol"
 	"wakey/internal/config"
 	"wakey/internal/devices/device"
+	"wakey/internal/devices/discover"
+	"wakey/internal/devices/imports"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
@@ -21,24 +26,62 @@ import (
 // Model for the Device component
 type Model struct {
 	devices []config.Device // list of devices to wake
-	keys    common.KeyMap
+	keys    keyMap
 	help    help.Model
 	table   table.Model
+	waking  *wakes                        // devices being woken and verified
+	states  map[string]config.StateUpdate // updates of the running refresh, by ID
+	poller  *refresh.Poller               // refreshes the states in the background
+	sender  wol.Sender                    // sends the magic packets
+	ctx     context.Context               // cancelled when the model is left
+	cancel  context.CancelFunc            // cancels ctx
+}
+
+// wakeResultMsg is sent when a device has been woken and verified
+type wakeResultMsg struct {
+	id       string
+	name     string
+	bootTime time.Duration
+	err      error
+}
+
+// wakeSentMsg is sent when the magic packets for a device have been sent
+type wakeSentMsg struct {
+	name       string
+	macAddress string
+	packets    int
+	err        error
+}
+
+// wakeTickMsg is sent every second while devices are being woken
+type wakeTickMsg struct{}
+
+// wakes are the devices being woken and verified. They are shared by the
+// copies of a model, so a wake that finishes while a form is open is still
+// recorded and shown when the list is back.
+type wakes struct {
+	mu       sync.Mutex
+	started  map[string]time.Time // when the wake of a device started, by ID
+	results  []wakeResultMsg      // finished wakes that haven't been shown yet
+	lastTick time.Time            // when the last wakeTickMsg was handled
 }
 
 // InitialModel function for the Device model
-func InitialModel() tea.Model {
-	// Get devices with updated state
-	devices := config.GetUpdateState().Devices
+func InitialModel(sender wol.Sender) tea.Model {
+	// Get devices, the state is refreshed in the background by Init
+	cfg := config.ReadConfig()
+	devices := cfg.Devices
 
 	// Define table columns
 	columns := []table.Column{
 		{Title: "ID", Width: 0},
-		{Title: "Device", Width: style.TermWidth * 15 / 100},
-		{Title: "Description", Width: style.TermWidth * 30 / 100},
-		{Title: "MAC Address", Width: style.TermWidth * 20 / 100},
-		{Title: "IP Address", Width: style.TermWidth * 20 / 100},
-		{Title: "State", Width: style.TermWidth * 15 / 100},
+		{Title: "Device", Width: style.TermWidth * 14 / 100},
+		{Title: "Description", Width: style.TermWidth * 20 / 100},
+		{Title: "MAC Address", Width: style.TermWidth * 16 / 100},
+		{Title: "Vendor", Width: style.TermWidth * 13 / 100},
+		{Title: "Address", Width: style.TermWidth * 15 / 100},
+		{Title: "State", Width: style.TermWidth * 13 / 100},
+		{Title: "RTT", Width: style.TermWidth * 9 / 100},
 	}
 
 	// Define table rows
@@ -49,8 +92,10 @@ func InitialModel() tea.Model {
 			device.DeviceName,
 			device.Description,
 			device.MacAddress,
+			wol.Vendor(device.MacAddress),
 			device.IPAddress,
 			device.State,
+			device.RTT,
 		}
 	}
 
@@ -77,44 +122,83 @@ func InitialModel() tea.Model {
 		Selected: s.Selected,
 	})
 
+	// Create the context for the background work of the model
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return Model{
 		// A list of devices to wake. This could be fetched from a database or config file
 		devices: devices,
 		// A map which indicates which devices are selected. We're using
 		// the  map like a mathematical set. The keys refer to the indexes
 		// of the `devices` slice, above.
-		keys:  common.DefaultKeyMap(),
-		help:  help.New(),
-		table: t,
+		keys:   keys,
+		help:   help.New(),
+		table:  t,
+		waking: &wakes{started: make(map[string]time.Time)},
+		states: make(map[string]config.StateUpdate),
+		poller: refresh.NewPoller(ctx, cfg.Settings.GetPollInterval()),
+		sender: sender,
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
 // Init function for the Device model
-func (m Model) Init() tea.Cmd { return nil }
+func (m Model) Init() tea.Cmd {
+	return m.poller.Init()
+}
+
+// Resume restarts the ticks of the polling and of the waking indicator when
+// the list is back from a form
+func (m Model) Resume() tea.Cmd {
+	return tea.Batch(m.poller.Resume(), m.waking.tick())
+}
+
+// Cancel stops the refresh and the wakes that are still running
+func (m Model) Cancel() {
+	m.cancel()
+}
 
 // Update function for the Device model
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
-	// Get new number of rows
-	rows := make([]table.Row, len(config.ReadConfig().Devices))
-
 	// Update the table with the new rows
-	m.table.SetRows(rows)
+	m.table.SetRows(m.convertDevicesToRows(config.ReadConfig().Devices))
 
-	// Define table rows
-	for i, device := range config.ReadConfig().Devices {
-		m.table.Rows()[i] = table.Row{
-			device.ID,
-			device.DeviceName,
-			device.Description,
-			device.MacAddress,
-			device.IPAddress,
-			device.State,
-		}
-	}
+	// Keep polling the state of the devices
+	cmds = append(cmds, m.poller.Update(msg))
 
 	switch msg := msg.(type) {
+	// Show the state of a device as soon as its probe finishes
+	case refresh.Msg:
+		m.states[msg.Update.DeviceID] = msg.Update
+
+		// Flag devices that were found on a new IP address
+		if msg.Update.IPAddress != "" {
+			if device, ok := config.ReadConfig().GetDevice(msg.Update.DeviceID); ok {
+				status.Message = fmt.Errorf("[%s] moved from %s to %s", device.DeviceName, msg.Update.PreviousIP, msg.Update.IPAddress)
+			}
+		}
+
+		m.table.SetRows(m.convertDevicesToRows(config.ReadConfig().Devices))
+
+	// The states have been written to the config file
+	case refresh.DoneMsg:
+		clear(m.states)
+
+	// Keep the waking indicator ticking while devices are being woken
+	case wakeTickMsg:
+		m.waking.ticked()
+
+	// The magic packets for a device have been sent
+	case wakeSentMsg:
+		if msg.err != nil {
+			status.Message = msg.err
+		} else {
+			status.Message = fmt.Errorf("waking up [%s] (%s), sent %d packet(s)", msg.name, msg.macAddress, msg.packets)
+		}
+
 	// Check if it was a key press
 	case tea.KeyMsg:
 		// Check which key was pressed
@@ -126,12 +210,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Edit):
 			// Get the selected device
 			selected := m.table.SelectedRow()
+			if selected == nil {
+				break
+			}
 			return device.InitialModel(m, selected), nil
 
 		// Delete device
 		case key.Matches(msg, m.keys.Delete):
 			// Get the selected device
 			selected := m.table.SelectedRow()
+			if selected == nil {
+				break
+			}
 
 			// Return popup message for confirmation
 			return popup.NewPopupMsg("Are you sure you want to delete "+selected[1]+" ("+selected[3]+")?", m, m.table, deleteDevice), nil
@@ -140,7 +230,32 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Refresh):
 			// return InitialModel to refresh the table
 			status.Message = fmt.Errorf("refreshing devices")
-			return InitialModel(), tea.ClearScreen
+			m.Cancel()
+			newModel := InitialModel(m.sender)
+			return newModel, tea.Batch(tea.ClearScreen, newModel.Init())
+
+		// Scan the network for devices to import
+		case key.Matches(msg, m.keys.Scan):
+			newModel := discover.InitialModel(m)
+			return newModel, newModel.Init()
+
+		// Browse the DNS-SD services on the network for devices to import
+		case key.Matches(msg, m.keys.Browse):
+			newModel := discover.InitialBrowseModel(m)
+			return newModel, newModel.Init()
+
+		// Import devices from the files of other tools
+		case key.Matches(msg, m.keys.Import):
+			newModel := imports.InitialModel(m)
+			return newModel, newModel.Init()
+
+		// Pause or resume polling
+		case key.Matches(msg, m.keys.Pause):
+			if m.poller.TogglePause() {
+				status.Message = fmt.Errorf("polling paused")
+			} else {
+				status.Message = fmt.Errorf("polling resumed")
+			}
 
 		// Toggle help
 		case key.Matches(msg, m.keys.Help):
@@ -150,19 +265,67 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Enter):
 			// Get the selected device
 			selected := m.table.SelectedRow()
-
-			// Wake the device
-			wol.WakeDevice(selected[3])
-
-			// Write the status message
+			if selected == nil {
+				break
+			}
+
+			// Look up the device so we can use its wake settings
+			cfg := config.ReadConfig()
+			device, ok := cfg.GetDevice(selected[0])
+			if !ok {
+				status.Message = fmt.Errorf("device [%s] not found", selected[1])
+				break
+			}
+
+			// Wake the device in the background, repeated packets and
+			// relays can take a while
+			cmds = append(cmds, m.wake(device, cfg.Settings))
 			status.Message = fmt.Errorf("waking up [%s] (%s)", selected[1], selected[3])
 
+		// Wake device and wait until it is online
+		case key.Matches(msg, m.keys.Verify):
+			// Get the selected device
+			selected := m.table.SelectedRow()
+			if selected == nil {
+				break
+			}
+
+			// Look up the device so we can use its wake settings
+			cfg := config.ReadConfig()
+			device, ok := cfg.GetDevice(selected[0])
+			if !ok {
+				status.Message = fmt.Errorf("device [%s] not found", selected[1])
+				break
+			}
+
+			// Don't wake a device twice
+			if !m.waking.start(device.ID) {
+				break
+			}
+
+			cmds = append(cmds, m.wakeAndWait(device, cfg.Settings))
+			status.Message = fmt.Errorf("waking up [%s] (%s) and waiting for it to come online", selected[1], selected[3])
+
 		// These keys should exit the program.
 		case key.Matches(msg, m.keys.Quit):
+			m.Cancel()
 			return m, tea.Quit
 		}
 	}
 
+	// Show the wakes that finished, also the ones that finished while a form
+	// was open
+	for _, result := range m.waking.take() {
+		if result.err != nil {
+			status.Message = fmt.Errorf("[%s] %v", result.name, result.err)
+		} else {
+			status.Message = fmt.Errorf("[%s] is online after %s", result.name, result.bootTime.Round(time.Second))
+		}
+	}
+
+	// Keep the waking indicator ticking, and restart it when a tick was lost
+	cmds = append(cmds, m.waking.tick())
+
 	// Update the table
 	newTable, cmd := m.table.Update(msg)
 	m.table = newTable
@@ -179,7 +342,7 @@ func (m Model) View() string {
 	newConfig := config.ReadConfig()
 
 	// Convert devices to table rows
-	rows := convertDevicesToRows(newConfig.Devices)
+	rows := m.convertDevicesToRows(newConfig.Devices)
 
 	// Truncate rows if they exceed the maximum number
 	if len(rows) > maxRows {
@@ -201,8 +364,8 @@ func (m Model) View() string {
 	// Render the table
 	s += m.table.View() + "\n"
 
-	// Show device count
-	s += style.CountStyle.Render(" Number of devices: "+strconv.Itoa(len(m.table.Rows()))) + "\n" // srtconv.Itoa converts int to string
+	// Show device count and when the states were last checked
+	s += style.CountStyle.Render(" Number of devices: "+strconv.Itoa(len(m.table.Rows()))+" · "+m.poller.Status()) + "\n" // srtconv.Itoa converts int to string
 
 	// Status message
 	var statusMessage string
@@ -220,16 +383,124 @@ func (m Model) View() string {
 }
 
 // convertDevicesToRows converts a slice of devices to a slice of table rows
-func convertDevicesToRows(devices []config.Device) []table.Row {
+func (m Model) convertDevicesToRows(devices []config.Device) []table.Row {
 	var rows []table.Row
 	for _, device := range devices {
+		// Show the results of the running refresh before they are written
+		if update, ok := m.states[device.ID]; ok {
+			update.Apply(&device)
+		}
+
+		state := device.State
+
+		// Show how long we have been waiting for devices that are waking
+		if start, ok := m.waking.since(device.ID); ok {
+			state = fmt.Sprintf("waking… %ds", int(time.Since(start).Seconds()))
+		}
+
+		// Show the address the device was found on when it differs
+		ip := device.IPAddress
+		if device.ResolvedIP != "" {
+			ip = fmt.Sprintf("%s (%s)", device.IPAddress, device.ResolvedIP)
+		}
+
 		rows = append(rows, table.Row{
-			device.ID, device.DeviceName, device.Description, device.MacAddress, device.IPAddress, device.State,
+			device.ID, device.DeviceName, device.Description, device.MacAddress, wol.Vendor(device.MacAddress), ip, state, device.RTT,
 		})
 	}
 	return rows
 }
 
+// wakeTick returns a command that sends a wakeTickMsg after a second
+func wakeTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return wakeTickMsg{}
+	})
+}
+
+// wake returns a command that sends the magic packets for the device
+func (m Model) wake(device config.Device, settings config.Settings) tea.Cmd {
+	return func() tea.Msg {
+		target := device.Target(settings)
+		err := wol.WakeDeviceContext(m.ctx, m.sender, target)
+		return wakeSentMsg{name: device.DeviceName, macAddress: device.MacAddress, packets: target.Packets(), err: err}
+	}
+}
+
+// wakeAndWait returns a command that wakes the device and waits for it to
+// come online
+func (m Model) wakeAndWait(device config.Device, settings config.Settings) tea.Cmd {
+	return func() tea.Msg {
+		bootTime, err := wol.WakeAndWaitCheck(m.ctx, m.sender, device.Target(settings), device.Check(), settings.GetWakeTimeout())
+		result := wakeResultMsg{id: device.ID, name: device.DeviceName, bootTime: bootTime, err: err}
+		m.waking.finish(result)
+		return result
+	}
+}
+
+// start records the start of the wake of a device and reports if it was
+// started, a device that is already being woken isn't woken twice
+func (w *wakes) start(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.started[id]; ok {
+		return false
+	}
+	w.started[id] = time.Now()
+	return true
+}
+
+// finish records the result of the wake of a device
+func (w *wakes) finish(result wakeResultMsg) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.started, result.id)
+	w.results = append(w.results, result)
+}
+
+// take returns the results that haven't been shown yet
+func (w *wakes) take() []wakeResultMsg {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	results := w.results
+	w.results = nil
+	return results
+}
+
+// since returns when the wake of the device started, if it is being woken
+func (w *wakes) since(id string) (time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	start, ok := w.started[id]
+	return start, ok
+}
+
+// ticked records that a wakeTickMsg has been handled, so tick sends the
+// next one
+func (w *wakes) ticked() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastTick = time.Time{}
+}
+
+// tick returns the command that sends the next wakeTickMsg while devices are
+// being woken, unless one is already on its way
+func (w *wakes) tick() tea.Cmd {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.started) == 0 || time.Since(w.lastTick) <= 2*time.Second {
+		return nil
+	}
+	w.lastTick = time.Now()
+	return wakeTick()
+}
+
 func deleteDevice(selectedRow []string) (error, error) {
 	currentConfig := config.ReadConfig()
 	for i, device := range currentConfig.Devices {
@@ -0,0 +1,440 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"wakey/internal/common/refresh"
+	"wakey/internal/common/status"
+	"wakey/internal/common/style"
+	"wakey/internal/common/wol"
+	"wakey/internal/config"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Description of the devices that are imported by a network scan
+const importDescription = "Found by network scan"
+
+// Candidate is a device found by a scan that can be imported
+type Candidate struct {
+	Name        string
+	Description string
+	IPAddress   string
+	MAC         string // empty when the MAC address couldn't be found
+}
+
+// Model is the model for the Discover component
+type Model struct {
+	previousModel tea.Model
+	keys          keyMap
+	help          help.Model
+	input         textinput.Model // network to scan, empty for the local networks
+	table         table.Model
+	candidates    []Candidate
+	known         map[string]bool // MAC addresses of the devices in the config
+	selected      map[int]bool    // indexes of the selected candidates
+	browse        bool            // browse DNS-SD services instead of scanning a network
+	scanning      bool
+	err           error
+	ctx           context.Context    // cancelled when the model is left
+	cancel        context.CancelFunc // cancels ctx
+}
+
+// scanMsg is sent when a scan has finished
+type scanMsg struct {
+	candidates []Candidate
+	err        error
+}
+
+// InitialBrowseModel returns the Discover component for devices that announce
+// DNS-SD services. The services are browsed as soon as the model starts.
+func InitialBrowseModel(previousModel tea.Model) Model {
+	m := InitialModel(previousModel)
+	m.browse = true
+	m.scanning = true
+	m.input.Blur()
+	m.table.Focus()
+	return m
+}
+
+// InitialModel returns the initial model for the Discover component
+func InitialModel(previousModel tea.Model) Model {
+	// The network to scan
+	ti := textinput.New()
+	ti.Cursor.Style = style.FocusedStyle
+	ti.CharLimit = 43
+	ti.Prompt = "Network : "
+	ti.Placeholder = "e.g. 192.168.1.0/24, empty for the local networks"
+	ti.PromptStyle = style.FocusedStyle
+	ti.TextStyle = style.FocusedStyle
+	ti.Focus()
+
+	// Define table columns
+	columns := []table.Column{
+		{Title: " ", Width: 3},
+		{Title: "Name", Width: style.TermWidth * 25 / 100},
+		{Title: "IP Address", Width: style.TermWidth * 15 / 100},
+		{Title: "MAC Address", Width: style.TermWidth * 18 / 100},
+		{Title: "Vendor", Width: style.TermWidth * 17 / 100},
+		{Title: "Known", Width: style.TermWidth * 10 / 100},
+	}
+
+	// Create the table model
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithHeight(10),
+	)
+
+	// Set the custom key bindings
+	t.KeyMap = table.KeyMap{
+		LineUp:   keys.Up,
+		LineDown: keys.Down,
+	}
+
+	// Get the default table styles
+	s := style.DefaultTableStyles()
+
+	// Set the styles
+	t.SetStyles(table.Styles{
+		Header:   s.Header,
+		Selected: s.Selected,
+	})
+
+	// Remember the MAC addresses that are already in the config
+	known := make(map[string]bool)
+	for _, device := range config.ReadConfig().Devices {
+		known[normalizeMAC(device.MacAddress)] = true
+	}
+
+	// Create the context for the scan
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return Model{
+		previousModel: previousModel,
+		keys:          keys,
+		help:          help.New(),
+		input:         ti,
+		table:         t,
+		known:         known,
+		selected:      make(map[int]bool),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Init function for the Discover model
+func (m Model) Init() tea.Cmd {
+	if m.browse {
+		return m.browseServices()
+	}
+	return textinput.Blink
+}
+
+// Update function for the Discover model
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	// The scan has finished
+	case scanMsg:
+		m.scanning = false
+		m.err = msg.err
+		m.candidates = msg.candidates
+		m.selected = make(map[int]bool)
+
+		// Move to the results
+		if len(m.candidates) > 0 || m.browse {
+			m.input.Blur()
+			m.table.Focus()
+			m.table.SetCursor(0)
+		}
+		m.table.SetRows(m.rows())
+		return m, nil
+
+	case tea.KeyMsg:
+		switch {
+		// Return to the list
+		case key.Matches(msg, m.keys.Quit):
+			m.cancel()
+			return m.previousModel, refresh.Resume(m.previousModel)
+
+		// Toggle help
+		case key.Matches(msg, m.keys.Help):
+			m.help.ShowAll = !m.help.ShowAll
+			return m, nil
+
+		// Scan the network
+		case key.Matches(msg, m.keys.Enter) && m.input.Focused():
+			if m.scanning {
+				return m, nil
+			}
+
+			networks, err := parseNetworks(m.input.Value())
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+
+			m.err = nil
+			m.scanning = true
+			return m, m.scan(networks)
+
+		// Import the selected devices
+		case key.Matches(msg, m.keys.Enter):
+			return m.importSelected()
+
+		// Move from the network to the results
+		case key.Matches(msg, m.keys.Down) && m.input.Focused() && msg.String() == "down":
+			if len(m.candidates) > 0 {
+				m.input.Blur()
+				m.table.Focus()
+			}
+			return m, nil
+
+		// Move from the results back to the network
+		case key.Matches(msg, m.keys.Up) && m.table.Focused() && m.table.Cursor() == 0 && !m.browse:
+			m.table.Blur()
+			return m, m.input.Focus()
+
+		// Select a device, known devices can't be imported twice
+		case key.Matches(msg, m.keys.Toggle) && m.table.Focused():
+			i := m.table.Cursor()
+			if i < len(m.candidates) && m.canImport(m.candidates[i]) {
+				m.selected[i] = !m.selected[i]
+			}
+			m.table.SetRows(m.rows())
+			return m, nil
+
+		// Select every device that isn't known
+		case key.Matches(msg, m.keys.All) && m.table.Focused():
+			for i, candidate := range m.candidates {
+				if m.canImport(candidate) {
+					m.selected[i] = true
+				}
+			}
+			m.table.SetRows(m.rows())
+			return m, nil
+		}
+	}
+
+	// Update the focused input or table
+	var cmd tea.Cmd
+	if m.input.Focused() {
+		m.input, cmd = m.input.Update(msg)
+	} else {
+		m.table, cmd = m.table.Update(msg)
+	}
+
+	return m, cmd
+}
+
+// View function for the Discover model
+func (m Model) View() string {
+	// The header
+	s := "\n"
+
+	title := "Devices > Discover"
+	if m.browse {
+		title = "Devices > Browse Services"
+	}
+	buttons := style.FocusedTab.Render(title)
+
+	s = lipgloss.PlaceHorizontal(style.TermWidth, lipgloss.Center, buttons) + "\n"
+
+	// Render the network input with the state of the scan
+	switch {
+	case m.browse && m.scanning:
+		s += style.ErrStyle("browsing DNS-SD services…") + "\n\n"
+	case m.browse && m.err != nil:
+		s += style.ErrStyle(m.err.Error()) + "\n\n"
+	case m.browse:
+		s += "\n\n"
+	case m.scanning:
+		s += lipgloss.JoinHorizontal(lipgloss.Left, m.input.View()+"   ", style.ErrStyle("scanning…")) + "\n\n"
+	case m.err != nil:
+		s += lipgloss.JoinHorizontal(lipgloss.Left, m.input.View()+"   ", style.ErrStyle(m.err.Error())) + "\n\n"
+	default:
+		s += m.input.View() + "\n\n"
+	}
+
+	// Render the results
+	s += m.table.View() + "\n"
+
+	// Show the number of devices found and selected
+	s += style.CountStyle.Render(" Found: "+strconv.Itoa(len(m.candidates))+" · Selected: "+strconv.Itoa(m.selectedCount())) + "\n"
+
+	// Help text
+	s += m.help.View(m.keys)
+
+	return s
+}
+
+// scan returns a command that scans the networks in the background
+func (m Model) scan(networks []*net.IPNet) tea.Cmd {
+	return func() tea.Msg {
+		discovered, err := wol.Discover(m.ctx, networks...)
+		if err != nil {
+			return scanMsg{err: err}
+		}
+
+		candidates := make([]Candidate, len(discovered))
+		for i, d := range discovered {
+			candidates[i] = Candidate{Name: name(d), Description: importDescription, IPAddress: d.IP.String(), MAC: d.MAC.String()}
+		}
+
+		return scanMsg{candidates: candidates}
+	}
+}
+
+// browseServices returns a command that browses DNS-SD services in the
+// background
+func (m Model) browseServices() tea.Cmd {
+	return func() tea.Msg {
+		discovered, err := wol.DiscoverServices(m.ctx)
+		if err != nil {
+			return scanMsg{err: err}
+		}
+
+		candidates := make([]Candidate, len(discovered))
+		for i, d := range discovered {
+			candidates[i] = Candidate{
+				Name:        d.Name,
+				Description: servicesDescription(d.Services),
+				IPAddress:   d.IP.String(),
+				MAC:         d.MAC.String(),
+			}
+		}
+
+		return scanMsg{candidates: candidates}
+	}
+}
+
+// canImport reports whether the candidate can be imported, which needs a MAC
+// address that isn't in the config yet
+func (m Model) canImport(candidate Candidate) bool {
+	return candidate.MAC != "" && !m.known[normalizeMAC(candidate.MAC)]
+}
+
+// importSelected adds the selected devices to the config and returns to the
+// list
+func (m Model) importSelected() (tea.Model, tea.Cmd) {
+	if m.selectedCount() == 0 {
+		m.err = fmt.Errorf("no devices selected")
+		return m, nil
+	}
+
+	// Add the selected devices to the config
+	currentConfig := config.ReadConfig()
+	for i, candidate := range m.candidates {
+		if m.selected[i] {
+			currentConfig.Devices = append(currentConfig.Devices, config.NewDevice(candidate.Name, candidate.Description, candidate.MAC, candidate.IPAddress))
+		}
+	}
+
+	// Write the the new version of the config to the file
+	config.WriteConfig(currentConfig)
+
+	// Set the status message
+	status.Message = fmt.Errorf("imported %d device(s)", m.selectedCount())
+
+	// Return to the list and clear the screen
+	m.cancel()
+	return m.previousModel, tea.Batch(tea.ClearScreen, refresh.Resume(m.previousModel))
+}
+
+// rows converts the candidates to table rows
+func (m Model) rows() []table.Row {
+	rows := make([]table.Row, len(m.candidates))
+	for i, candidate := range m.candidates {
+		check := "[ ]"
+		if m.selected[i] {
+			check = "[x]"
+		}
+
+		known := ""
+		switch {
+		case candidate.MAC == "":
+			check = " - "
+			known = "no MAC"
+		case m.known[normalizeMAC(candidate.MAC)]:
+			check = " - "
+			known = "known"
+		}
+
+		rows[i] = table.Row{check, candidate.Name, candidate.IPAddress, candidate.MAC, wol.Vendor(candidate.MAC), known}
+	}
+	return rows
+}
+
+// selectedCount returns the number of selected devices
+func (m Model) selectedCount() int {
+	count := 0
+	for _, selected := range m.selected {
+		if selected {
+			count++
+		}
+	}
+	return count
+}
+
+// parseNetworks parses the network to scan, no networks means the local
+// networks are scanned
+func parseNetworks(value string) ([]*net.IPNet, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	_, network, err := net.ParseCIDR(value)
+	if err != nil || network.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid network, e.g. 192.168.1.0/24")
+	}
+
+	if ones, _ := network.Mask.Size(); 1<<(32-ones) > wol.MaxSweepHosts {
+		return nil, fmt.Errorf("network must have at most %d addresses", wol.MaxSweepHosts)
+	}
+
+	return []*net.IPNet{network}, nil
+}
+
+// name returns the name of a discovered device, the first label of its host
+// name or its IP address
+func name(d wol.Discovered) string {
+	if d.Hostname == "" {
+		return d.IP.String()
+	}
+
+	label, _, _ := strings.Cut(d.Hostname, ".")
+	return label
+}
+
+// servicesDescription describes a device by the services it announced, e.g.
+// "Services: smb, ssh"
+func servicesDescription(services []string) string {
+	names := make([]string, len(services))
+	for i, service := range services {
+		name, _, _ := strings.Cut(strings.TrimPrefix(service, "_"), ".")
+		names[i] = name
+	}
+
+	description := "Services: " + strings.Join(names, ", ")
+	if len(description) > config.MaxDescription {
+		description = description[:config.MaxDescription-len("…")] + "…"
+	}
+	return description
+}
+
+// normalizeMAC returns the MAC address in lower case with colons so
+// addresses written differently can be compared
+func normalizeMAC(mac string) string {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return strings.ToLower(mac)
+	}
+	return hwAddr.String()
+}
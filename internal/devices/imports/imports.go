@@ -0,0 +1,285 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"wakey/internal/common/refresh"
+	"wakey/internal/common/status"
+	"wakey/internal/common/style"
+	"wakey/internal/config"
+	"wakey/internal/importer"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Model is the model for the Import component
+type Model struct {
+	previousModel tea.Model
+	keys          keyMap
+	help          help.Model
+	focusIndex    int               // index of the focused input, len(inputs) for the preview
+	inputs        []textinput.Model // file, format and whether to update devices
+	table         table.Model
+	changes       []importer.Change // changes of the last preview
+	err           error
+}
+
+// InitialModel returns the initial model for the Import component
+func InitialModel(previousModel tea.Model) Model {
+	m := Model{
+		previousModel: previousModel,
+		keys:          keys,
+		help:          help.New(),
+		inputs:        make([]textinput.Model, 3),
+	}
+
+	for i := range m.inputs {
+		ti := textinput.New()
+		ti.Cursor.Style = style.FocusedStyle
+		ti.CharLimit = 256
+
+		switch i {
+		// File to import
+		case 0:
+			ti.Prompt = "File   : "
+			ti.Placeholder = "e.g. /etc/ethers or ~/wakemeonlan.csv"
+			ti.Focus()
+			ti.PromptStyle = style.FocusedStyle
+			ti.TextStyle = style.FocusedStyle
+		// Format of the file
+		case 1:
+			ti.Prompt = "Format : "
+			ti.Placeholder = "auto or one of " + strings.Join(importer.FormatNames(), ", ")
+			ti.CharLimit = 16
+		// Update the devices that are already in the config
+		case 2:
+			ti.Prompt = "Update : "
+			ti.Placeholder = "yes or no, update the IP address of known devices"
+			ti.CharLimit = 3
+		}
+
+		m.inputs[i] = ti
+	}
+
+	// Define table columns
+	columns := []table.Column{
+		{Title: "Action", Width: style.TermWidth * 10 / 100},
+		{Title: "Name", Width: style.TermWidth * 25 / 100},
+		{Title: "MAC Address", Width: style.TermWidth * 20 / 100},
+		{Title: "Address", Width: style.TermWidth * 35 / 100},
+	}
+
+	// Create the table model
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithHeight(10),
+	)
+
+	// Set the custom key bindings
+	t.KeyMap = table.KeyMap{
+		LineUp:   keys.Up,
+		LineDown: keys.Down,
+	}
+
+	// Get the default table styles
+	s := style.DefaultTableStyles()
+
+	// Set the styles
+	t.SetStyles(table.Styles{
+		Header:   s.Header,
+		Selected: s.Selected,
+	})
+
+	m.table = t
+
+	return m
+}
+
+// Init function for the Import model
+func (m Model) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update function for the Import model
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		// Return to the list
+		case key.Matches(msg, m.keys.Quit):
+			return m.previousModel, refresh.Resume(m.previousModel)
+
+		// Toggle help
+		case key.Matches(msg, m.keys.Help):
+			m.help.ShowAll = !m.help.ShowAll
+			return m, nil
+
+		// Import the previewed changes
+		case key.Matches(msg, m.keys.Enter) && m.table.Focused():
+			return m.importChanges()
+
+		// Preview the changes of the file
+		case key.Matches(msg, m.keys.Enter):
+			m.preview()
+			if len(m.changes) > 0 {
+				return m, m.setFocus(len(m.inputs))
+			}
+			return m, nil
+
+		// Move from the top of the preview back to the inputs
+		case key.Matches(msg, m.keys.Up) && m.table.Focused() && m.table.Cursor() == 0:
+			return m, m.setFocus(len(m.inputs) - 1)
+
+		// Move between the inputs and to the preview
+		case key.Matches(msg, m.keys.Up) && !m.table.Focused():
+			return m, m.setFocus(max(m.focusIndex-1, 0))
+
+		case key.Matches(msg, m.keys.Down) && !m.table.Focused():
+			if m.focusIndex == len(m.inputs)-1 && len(m.changes) == 0 {
+				return m, nil
+			}
+			return m, m.setFocus(m.focusIndex + 1)
+		}
+	}
+
+	// Update the focused input or table
+	var cmd tea.Cmd
+	if m.table.Focused() {
+		m.table, cmd = m.table.Update(msg)
+	} else {
+		m.inputs[m.focusIndex], cmd = m.inputs[m.focusIndex].Update(msg)
+	}
+
+	return m, cmd
+}
+
+// View function for the Import model
+func (m Model) View() string {
+	// The header
+	s := "\n"
+
+	buttons := style.FocusedTab.Render("Devices > Import")
+
+	s = lipgloss.PlaceHorizontal(style.TermWidth, lipgloss.Center, buttons) + "\n"
+
+	// Render the inputs
+	for _, input := range m.inputs {
+		s += input.View() + "\n"
+	}
+
+	if m.err != nil {
+		s += style.ErrStyle(m.err.Error()) + "\n\n"
+	} else {
+		s += "\n\n"
+	}
+
+	// Render the preview
+	s += m.table.View() + "\n"
+
+	// Show what the import will change
+	s += style.CountStyle.Render(" "+importer.Summary(m.changes)) + "\n"
+
+	// Help text
+	s += m.help.View(m.keys)
+
+	return s
+}
+
+// setFocus focuses the input with the index, or the preview when the index
+// is past the inputs
+func (m *Model) setFocus(index int) tea.Cmd {
+	m.focusIndex = index
+
+	for i := range m.inputs {
+		m.inputs[i].Blur()
+		m.inputs[i].PromptStyle = style.NoStyle
+		m.inputs[i].TextStyle = style.NoStyle
+	}
+
+	if index >= len(m.inputs) {
+		m.table.Focus()
+		return nil
+	}
+
+	m.table.Blur()
+	m.inputs[index].PromptStyle = style.FocusedStyle
+	m.inputs[index].TextStyle = style.FocusedStyle
+	return m.inputs[index].Focus()
+}
+
+// preview reads the file and shows what importing it would change
+func (m *Model) preview() {
+	m.changes = nil
+	m.table.SetRows(nil)
+
+	path := m.inputs[0].Value()
+	if path == "" {
+		m.err = fmt.Errorf("file is required")
+		return
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		path = filepath.Join(config.HomeDir, rest)
+	}
+
+	update := m.inputs[2].Value()
+	if update != "" && update != "yes" && update != "no" {
+		m.err = fmt.Errorf("update must be yes or no")
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		m.err = err
+		return
+	}
+	defer file.Close()
+
+	devices, err := importer.Read(file, m.inputs[1].Value())
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	m.err = nil
+	m.changes = importer.Merge(config.ReadConfig(), devices, update == "yes")
+	if len(m.changes) == 0 {
+		m.err = fmt.Errorf("no devices found")
+	}
+	m.table.SetRows(m.rows())
+	m.table.SetCursor(0)
+}
+
+// importChanges writes the previewed changes to the config and returns to
+// the list
+func (m Model) importChanges() (tea.Model, tea.Cmd) {
+	added := importer.Count(m.changes, importer.ActionAdd)
+	updated := importer.Count(m.changes, importer.ActionUpdate)
+	if added+updated == 0 {
+		m.err = fmt.Errorf("nothing to import, the devices are already in the list")
+		return m, nil
+	}
+
+	// Write the the new version of the config to the file
+	config.WriteConfig(importer.Apply(config.ReadConfig(), m.changes))
+
+	// Set the status message
+	status.Message = fmt.Errorf("imported %d device(s), updated %d device(s)", added, updated)
+
+	// Return to the list and clear the screen
+	return m.previousModel, tea.Batch(tea.ClearScreen, refresh.Resume(m.previousModel))
+}
+
+// rows converts the changes to table rows
+func (m Model) rows() []table.Row {
+	rows := make([]table.Row, len(m.changes))
+	for i, change := range m.changes {
+		rows[i] = table.Row{string(change.Action), change.Device.DeviceName, change.Device.MacAddress, change.Address()}
+	}
+	return rows
+}
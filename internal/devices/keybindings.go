@@ -8,11 +8,16 @@ type keyMap struct {
 	Up      key.Binding
 	Down    key.Binding
 	Enter   key.Binding
+	Verify  key.Binding
 	Create  key.Binding
 	Edit    key.Binding
 	Delete  key.Binding
+	Scan    key.Binding
+	Browse  key.Binding
+	Import  key.Binding
 	View    key.Binding
 	Refresh key.Binding
+	Pause   key.Binding
 	Help    key.Binding
 	Quit    key.Binding
 }
@@ -28,8 +33,8 @@ func (k keyMap) ShortHelp() []key.Binding {
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down}, // first column
-		{k.Enter, k.Create, k.Edit, k.Delete, k.Refresh}, // second column
-		{k.Help, k.View, k.Quit},                         // third column
+		{k.Enter, k.Verify, k.Create, k.Edit, k.Delete, k.Scan, k.Browse, k.Import, k.Refresh, k.Pause}, // second column
+		{k.Help, k.View, k.Quit}, // third column
 	}
 }
 
@@ -47,6 +52,10 @@ var keys = keyMap{
 		key.WithKeys("enter"),
 		key.WithHelp("enter", "wake"),
 	),
+	Verify: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "wake and wait"),
+	),
 	Create: key.NewBinding(
 		key.WithKeys("c"),
 		key.WithHelp("c", "create device"),
@@ -59,6 +68,18 @@ var keys = keyMap{
 		key.WithKeys("d"),
 		key.WithHelp("d", "delete device"),
 	),
+	Scan: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "scan network"),
+	),
+	Browse: key.NewBinding(
+		key.WithKeys("b"),
+		key.WithHelp("b", "browse services"),
+	),
+	Import: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "import devices"),
+	),
 	View: key.NewBinding(
 		key.WithKeys("tab"),
 		key.WithHelp("tab", "switch view"),
@@ -67,6 +88,10 @@ var keys = keyMap{
 		key.WithKeys("r"),
 		key.WithHelp("r", "refresh"),
 	),
+	Pause: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "pause polling"),
+	),
 	Help: key.NewBinding(
 		key.WithKeys("ctrl+h"),
 		key.WithHelp("ctrl+h", "toggle help"),
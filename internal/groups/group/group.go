@@ -3,6 +3,7 @@ package group
 import (
 	"fmt"
 	"strings"
+	"wakey/internal/common/refresh"
 	"wakey/internal/common/status"
 	"wakey/internal/common/style"
 	"wakey/internal/config"
@@ -123,7 +124,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch {
 		// Return to the list
 		case key.Matches(msg, m.keys.Quit):
-			return m.previousModel, nil
+			return m.previousModel, refresh.Resume(m.previousModel)
 
 		// Toggle help
 		case key.Matches(msg, m.keys.Help):
@@ -167,15 +168,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Replace the device names with device IDs
 					deviceValue = convertDeviceNamesToIDs(deviceValue, existingDevices)
 
+					// Read the config again, the states are refreshed in the
+					// background while the form is open
+					currentConfig := config.ReadConfig()
+
 					// Check if we are editing an existing group
 					if m.selectedRow != nil {
 						// Get the selected group
 						selected := m.selectedRow
 
 						// Update the group in the config
-						for i, group := range m.currentConfig.Groups {
+						for i, group := range currentConfig.Groups {
 							if group.ID == selected[0] {
-								m.currentConfig.Groups[i] = config.Group{
+								currentConfig.Groups[i] = config.Group{
 									ID:        group.ID,
 									GroupName: m.inputs[0].Value(),
 									Devices:   deviceValue,
@@ -185,29 +190,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 					} else {
 						// Append the group to the config
-						updatedGroups := append(m.currentConfig.Groups, config.Group{
+						currentConfig.Groups = append(currentConfig.Groups, config.Group{
 							ID:        uuid.NewString(),
 							GroupName: m.inputs[0].Value(),
 							Devices:   deviceValue,
 						})
-
-						// Create a new config with the updated group
-						m.currentConfig = config.Config{
-							Devices: m.currentConfig.Devices,
-							Groups:  updatedGroups,
-						}
 					}
 
 					// Write the the new version of the config to the file
-					config.WriteConfig(m.currentConfig)
+					config.WriteConfig(currentConfig)
 
 					// Set the status message
 					status.Message = fmt.Errorf("group [%s] added", m.inputs[0].Value())
 
 					// Return to the list and clear the screen
-					return m.previousModel, func() tea.Msg {
-						return tea.ClearScreen()
-					}
+					return m.previousModel, tea.Batch(tea.ClearScreen, refresh.Resume(m.previousModel))
 				}
 			}
 
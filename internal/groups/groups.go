@@ -1,11 +1,14 @@
 package groups
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"wakey/internal/common"
 	"wakey/internal/common/popup"
+	"wakey/internal/common/refresh"
 	"wakey/internal/common/status"
 	"wakey/internal/common/style"
 	"wakey/internal/common/wol"
@@ -25,21 +28,46 @@ type Model struct {
 	keys   common.KeyMap
 	help   help.Model
 	table  table.Model
+	poller *refresh.Poller    // refreshes the states in the background
+	sender wol.Sender         // sends the magic packets
+	ctx    context.Context    // cancelled when the model is left
+	cancel context.CancelFunc // cancels ctx
+}
+
+// wakeGroupMsg is sent when the magic packets for a group have been sent
+type wakeGroupMsg struct {
+	name    string
+	results []wol.Result
+	names   map[string]string // device names by ID
 }
 
 // Init function for the Device model
-func (m Model) Init() tea.Cmd { return nil }
+func (m Model) Init() tea.Cmd {
+	return m.poller.Init()
+}
+
+// Resume restarts the ticks of the polling when the list is back from a form
+func (m Model) Resume() tea.Cmd {
+	return m.poller.Resume()
+}
+
+// Cancel stops the refresh that is still running
+func (m Model) Cancel() {
+	m.cancel()
+}
 
 // InitialModel function for the Group model
-func InitialModel() tea.Model {
-	// Get groups with updated state
-	groups := config.GetUpdateState().Groups
+func InitialModel(sender wol.Sender) tea.Model {
+	// Get groups, the state of the devices is refreshed in the background by Init
+	cfg := config.ReadConfig()
+	groups := cfg.Groups
 
 	// Define table columns
 	columns := []table.Column{
 		{Title: "ID", Width: 0},
-		{Title: "Group Name", Width: style.TermWidth / 2},
-		{Title: "Devices", Width: style.TermWidth / 2},
+		{Title: "Group Name", Width: style.TermWidth * 30 / 100},
+		{Title: "Devices", Width: style.TermWidth * 55 / 100},
+		{Title: "Online", Width: style.TermWidth * 15 / 100},
 	}
 
 	// Define table rows
@@ -85,15 +113,22 @@ func InitialModel() tea.Model {
 		Selected: s.Selected,
 	})
 
+	// Create the context for the background work of the model
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return Model{
 		// A list of devices to wake. This could be fetched from a database or config file
 		groups: groups,
 		// A map which indicates which devices are selected. We're using
 		// the  map like a mathematical set. The keys refer to the indexes
 		// of the `devices` slice, above.
-		keys:  common.DefaultKeyMap(),
-		help:  help.New(),
-		table: t,
+		keys:   common.DefaultKeyMap(),
+		help:   help.New(),
+		table:  t,
+		poller: refresh.NewPoller(ctx, cfg.Settings.GetPollInterval()),
+		sender: sender,
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
@@ -107,16 +142,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.table.SetRows(rows)
 
 	// Define table rows
-	for i, group := range config.ReadConfig().Groups {
+	cfg := config.ReadConfig()
+	for i, group := range cfg.Groups {
 		deviceValue := strings.Join(group.Devices, ", ")
 		m.table.Rows()[i] = table.Row{
 			group.ID,
 			group.GroupName,
 			deviceValue,
+			onlineCount(group, cfg.Devices),
 		}
 	}
 
+	// Keep polling the state of the devices
+	cmds = append(cmds, m.poller.Update(msg))
+
 	switch msg := msg.(type) {
+	// The magic packets for a group have been sent
+	case wakeGroupMsg:
+		status.Message = wakeGroupStatus(msg.name, msg.results, msg.names)
+
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.keys.Create):
@@ -125,12 +169,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Edit):
 			// Edit the selected group
 			selected := m.table.SelectedRow()
+			if selected == nil {
+				break
+			}
 
 			return group.InitialModel(m, selected), nil
 
 		case key.Matches(msg, m.keys.Delete):
 			// Delete the selected group
 			selected := m.table.SelectedRow()
+			if selected == nil {
+				break
+			}
 
 			// Return popup message for confirmation
 			return popup.NewPopupMsg("Are you sure you want to delete "+selected[1]+"?", m, m.table, deleteGroup), nil
@@ -138,29 +188,36 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Enter):
 			// Extract the selected group and get the device IDs
 			selected := m.table.SelectedRow()
+			if selected == nil {
+				break
+			}
 			deviceIDs := selected[2]
 
 			// Split the device IDs into an array
 			deviceIDsArr := strings.Split(deviceIDs, ", ")
 
-			// Create a map of device IDs to MAC addresses
-			deviceMap := createDeviceMacAddressMap(config.ReadConfig().Devices)
+			// Get the wake targets for the device IDs, devices that have
+			// been deleted fail right away
+			cfg := config.ReadConfig()
+			targets, missing := getTargets(deviceIDsArr, cfg)
 
-			// Get the MAC addresses for the device IDs
-			macAddresses := getMacAddresses(deviceIDsArr, deviceMap)
+			// Wake the group in the background and report the results
+			cmds = append(cmds, m.wakeGroup(selected[1], targets, missing, createDeviceNameMap(cfg.Devices)))
+			status.Message = fmt.Errorf("waking up group [%s]", selected[1])
 
-			// Wake the group using the MAC addresses
-			err := wol.WakeGroup(macAddresses)
-			if err != nil {
-				status.Message = err
+		case key.Matches(msg, m.keys.Pause):
+			// Pause or resume polling
+			if m.poller.TogglePause() {
+				status.Message = fmt.Errorf("polling paused")
 			} else {
-				status.Message = fmt.Errorf("waking [%s] group", selected[1])
+				status.Message = fmt.Errorf("polling resumed")
 			}
 
 		case key.Matches(msg, m.keys.Help):
 			m.help.ShowAll = !m.help.ShowAll
 
 		case key.Matches(msg, m.keys.Quit):
+			m.Cancel()
 			return m, tea.Quit
 		}
 	}
@@ -194,7 +251,7 @@ func (m Model) View() string {
 		}
 		deviceNamesStr := strings.Join(deviceNames, ", ")
 
-		rows = append(rows, table.Row{group.ID, group.GroupName, deviceNamesStr})
+		rows = append(rows, table.Row{group.ID, group.GroupName, deviceNamesStr, onlineCount(group, newConfig.Devices)})
 	}
 
 	// Truncate rows if they exceed the maximum number
@@ -217,8 +274,8 @@ func (m Model) View() string {
 	// Render the table
 	s += m.table.View() + "\n"
 
-	// Group count
-	s += style.CountStyle.Render(" Number of devices: "+strconv.Itoa(len(m.table.Rows()))) + "\n" // srtconv.Itoa converts int to string
+	// Group count and when the states were last checked
+	s += style.CountStyle.Render(" Number of devices: "+strconv.Itoa(len(m.table.Rows()))+" · "+m.poller.Status()) + "\n" // srtconv.Itoa converts int to string
 
 	// Status message
 	var statusMessage string
@@ -234,6 +291,17 @@ func (m Model) View() string {
 	return s
 }
 
+// onlineCount returns how many devices of the group are online, e.g. "2/3"
+func onlineCount(group config.Group, devices []config.Device) string {
+	online := 0
+	for _, device := range devices {
+		if device.State == string(wol.StateOnline) && slices.Contains(group.Devices, device.ID) {
+			online++
+		}
+	}
+	return fmt.Sprintf("%d/%d", online, len(group.Devices))
+}
+
 // createDeviceAttributeMap creates a map of device IDs to a specified attribute
 func createDeviceAttributeMap(devices []config.Device, attributeFunc func(config.Device) string) map[string]string {
 	deviceMap := make(map[string]string)
@@ -250,22 +318,52 @@ func createDeviceNameMap(devices []config.Device) map[string]string {
 	})
 }
 
-// createDeviceMacAddressMap creates a map of device IDs to MAC addresses
-func createDeviceMacAddressMap(devices []config.Device) map[string]string {
-	return createDeviceAttributeMap(devices, func(d config.Device) string {
-		return d.MacAddress
-	})
+// wakeGroup returns a command that sends the magic packets for the targets
+// of a group. The missing devices are reported after the woken ones.
+func (m Model) wakeGroup(name string, targets []wol.Target, missing []wol.Result, names map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		results := append(wol.WakeGroupContext(m.ctx, m.sender, targets), missing...)
+		return wakeGroupMsg{name: name, results: results, names: names}
+	}
 }
 
-// getMacAddresses returns the MAC addresses for the given device IDs
-func getMacAddresses(deviceIDs []string, deviceMap map[string]string) []string {
-	var macAddresses []string
+// getTargets returns the wake targets for the given device IDs, and a failed
+// result for each device that isn't in the config anymore
+func getTargets(deviceIDs []string, cfg config.Config) ([]wol.Target, []wol.Result) {
+	var targets []wol.Target
+	var missing []wol.Result
 	for _, deviceID := range deviceIDs {
-		if macAddress, ok := deviceMap[deviceID]; ok {
-			macAddresses = append(macAddresses, macAddress)
+		if deviceID == "" {
+			continue
+		}
+		if device, ok := cfg.GetDevice(deviceID); ok {
+			targets = append(targets, device.Target(cfg.Settings))
+		} else {
+			missing = append(missing, wol.Result{DeviceID: deviceID, Err: fmt.Errorf("device not found")})
 		}
 	}
-	return macAddresses
+	return targets, missing
+}
+
+// wakeGroupStatus summarizes the results of waking a group for the status bar
+func wakeGroupStatus(groupName string, results []wol.Result, deviceNameMap map[string]string) error {
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			// Deleted devices have no name
+			name, ok := deviceNameMap[result.DeviceID]
+			if !ok {
+				name = result.DeviceID
+			}
+			failed = append(failed, fmt.Sprintf("%s (%v)", name, result.Err))
+		}
+	}
+
+	woke := len(results) - len(failed)
+	if len(failed) > 0 {
+		return fmt.Errorf("waking [%s] group: woke %d/%d, failed: %s", groupName, woke, len(results), strings.Join(failed, ", "))
+	}
+	return fmt.Errorf("waking [%s] group: woke %d/%d", groupName, woke, len(results))
 }
 
 func deleteGroup(selectedRow []string) (error, error) {
@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"wakey/internal/common/wol"
+)
+
+// parseEthers reads /etc/ethers, which has a MAC address and a host name or
+// IP address on every line, e.g. "00:11:32:aa:bb:cc nas.lan".
+func parseEthers(data []byte) ([]Entry, error) {
+	var entries []Entry
+	for _, line := range lines(data) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+
+		// Host names are valid addresses, they are resolved when probing
+		entry := Entry{MAC: fields[0], IPAddress: fields[1]}
+		if net.ParseIP(fields[1]) == nil {
+			entry.Name = strings.Split(fields[1], ".")[0]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func detectEthers(data []byte) bool {
+	return allLines(data, func(fields []string) bool {
+		_, err := net.ParseMAC(fields[0])
+		return len(fields) == 2 && err == nil
+	})
+}
+
+// parseDnsmasq reads a dnsmasq lease file, which has the expiry time, MAC
+// address, IP address, host name and client ID of a lease on every line, e.g.
+// "1717171717 00:11:32:aa:bb:cc 192.168.1.10 nas 01:00:11:32:aa:bb:cc". DHCPv6
+// leases have no MAC address and are skipped.
+func parseDnsmasq(data []byte) ([]Entry, error) {
+	var entries []Entry
+	for _, line := range lines(data) {
+		fields := strings.Fields(line)
+
+		// The DUID of the server comes before the DHCPv6 leases
+		if fields[0] == "duid" {
+			continue
+		}
+
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+
+		// Leases of clients that didn't send a host name have a *
+		name := fields[3]
+		if name == "*" {
+			name = ""
+		}
+		entries = append(entries, Entry{Name: name, MAC: fields[1], IPAddress: fields[2]})
+	}
+	return entries, nil
+}
+
+func detectDnsmasq(data []byte) bool {
+	return allLines(data, func(fields []string) bool {
+		if fields[0] == "duid" {
+			return true
+		}
+		_, err := strconv.ParseInt(fields[0], 10, 64)
+		return len(fields) >= 4 && err == nil && net.ParseIP(fields[2]) != nil
+	})
+}
+
+var (
+	reLease          = regexp.MustCompile(`^lease\s+(\S+)\s*\{$`)
+	reHardware       = regexp.MustCompile(`^hardware\s+ethernet\s+([0-9A-Fa-f:]+);$`)
+	reClientHostname = regexp.MustCompile(`^client-hostname\s+"([^"]*)";$`)
+)
+
+// parseDhcpd reads an ISC dhcpd.leases file. Every lease is a block like
+//
+//	lease 192.168.1.10 {
+//	  hardware ethernet 00:11:32:aa:bb:cc;
+//	  client-hostname "nas";
+//	}
+//
+// The file is appended to when a lease changes, so the last lease of a MAC
+// address is the newest.
+func parseDhcpd(data []byte) ([]Entry, error) {
+	var entries []Entry
+	var lease *Entry
+
+	for _, line := range lines(data) {
+		if lease == nil {
+			if match := reLease.FindStringSubmatch(line); match != nil {
+				lease = &Entry{IPAddress: match[1]}
+			}
+			continue
+		}
+
+		switch {
+		case line == "}":
+			entries = append(entries, *lease)
+			lease = nil
+		case reHardware.MatchString(line):
+			lease.MAC = reHardware.FindStringSubmatch(line)[1]
+		case reClientHostname.MatchString(line):
+			lease.Name = reClientHostname.FindStringSubmatch(line)[1]
+		}
+	}
+
+	if lease != nil {
+		return nil, fmt.Errorf("lease %s is not closed", lease.IPAddress)
+	}
+	return entries, nil
+}
+
+func detectDhcpd(data []byte) bool {
+	for _, line := range lines(data) {
+		if reLease.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNeigh reads the output of ip neigh or arp -a. Link-local IPv6
+// addresses are skipped and IPv4 addresses are preferred, since they are
+// what the devices are usually reached on.
+func parseNeigh(data []byte) ([]Entry, error) {
+	neighbors, err := wol.ParseNeighbors(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var ipv4, ipv6 []Entry
+	for _, neighbor := range neighbors {
+		entry := Entry{MAC: neighbor.MAC.String(), IPAddress: neighbor.IP.String()}
+		switch {
+		case neighbor.IP.To4() != nil:
+			ipv4 = append(ipv4, entry)
+		case !neighbor.IP.IsLinkLocalUnicast():
+			ipv6 = append(ipv6, entry)
+		}
+	}
+
+	// Later entries replace earlier ones of the same MAC address
+	return append(ipv6, ipv4...), nil
+}
+
+func detectNeigh(data []byte) bool {
+	neighbors, err := wol.ParseNeighbors(bytes.NewReader(data))
+	return err == nil && len(neighbors) > 0
+}
@@ -0,0 +1,187 @@
+// Package importer reads devices from the files of other tools, like
+// /etc/ethers and DHCP leases, and merges them into the config.
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"wakey/internal/common/wol"
+	"wakey/internal/config"
+)
+
+// Format is a file format devices can be imported from.
+type Format struct {
+	Name        string
+	Description string
+	parse       func(data []byte) ([]Entry, error)
+	detect      func(data []byte) bool // reports whether the data looks like the format, nil if it can't be told apart
+}
+
+// Entry is a device read from a file before it is turned into a device.
+type Entry struct {
+	Name             string
+	Description      string
+	MAC              string
+	IPAddress        string
+	BroadcastAddress string
+	Port             int
+	SecureOn         string
+}
+
+// Formats are the formats devices can be imported from, in the order they
+// are detected. Formats with a header come first, since the lines of other
+// formats can look like the lines of a neighbor table.
+var Formats = []Format{
+	{"wakemeonlan", "WakeMeOnLan CSV export", parseWakeMeOnLan, detectWakeMeOnLan},
+	{"dhcpd", "ISC dhcpd.leases", parseDhcpd, detectDhcpd},
+	{"dnsmasq", "dnsmasq leases", parseDnsmasq, detectDnsmasq},
+	{"ethers", "/etc/ethers", parseEthers, detectEthers},
+	{"neigh", "ip neigh or arp -a output", parseNeigh, detectNeigh},
+	{"wakeonlan", "wakeonlan host file", parseWakeonlan, nil},
+	{"wol", "wol file", parseWol, nil},
+}
+
+// FormatNames returns the names of the formats.
+func FormatNames() []string {
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = format.Name
+	}
+	return names
+}
+
+// GetFormat returns the format with the given name.
+func GetFormat(name string) (Format, bool) {
+	for _, format := range Formats {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Detect returns the format the data looks like.
+func Detect(data []byte) (Format, error) {
+	for _, format := range Formats {
+		if format.detect != nil && format.detect(data) {
+			return format, nil
+		}
+	}
+	return Format{}, fmt.Errorf("unknown format, use one of %s", strings.Join(FormatNames(), ", "))
+}
+
+// Read reads the devices from r in the format with the given name, or in the
+// detected format when the name is empty or "auto".
+func Read(r io.Reader, name string) ([]config.Device, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var format Format
+	switch name {
+	case "", "auto":
+		if format, err = Detect(data); err != nil {
+			return nil, err
+		}
+	default:
+		var ok bool
+		if format, ok = GetFormat(name); !ok {
+			return nil, fmt.Errorf("unknown format %s, use one of %s", name, strings.Join(FormatNames(), ", "))
+		}
+	}
+
+	entries, err := format.parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", format.Description, err)
+	}
+
+	return format.devices(entries), nil
+}
+
+// devices turns the entries into devices. Entries without a valid unicast MAC
+// address are skipped and later entries for the same MAC address replace
+// earlier ones, like a newer lease replaces an older one.
+func (f Format) devices(entries []Entry) []config.Device {
+	var devices []config.Device
+	index := make(map[string]int)
+
+	for _, entry := range entries {
+		mac := normalizeMAC(entry.MAC)
+		if mac == "" {
+			continue
+		}
+
+		// Name the device after its address when it has no name
+		name := entry.Name
+		if name == "" {
+			name = entry.IPAddress
+		}
+		if name == "" {
+			name = mac
+		}
+
+		// The device form needs a description
+		description := entry.Description
+		if description == "" {
+			description = wol.Vendor(mac)
+		}
+		if description == "" {
+			description = "Imported from " + f.Description
+		}
+		if len(description) > config.MaxDescription {
+			description = strings.ToValidUTF8(description[:config.MaxDescription], "")
+		}
+
+		d := config.NewDevice(name, description, mac, entry.IPAddress)
+		d.BroadcastAddress = entry.BroadcastAddress
+		d.Port = entry.Port
+		d.SecureOn = entry.SecureOn
+
+		if i, ok := index[mac]; ok {
+			d.ID = devices[i].ID
+			devices[i] = d
+			continue
+		}
+		index[mac] = len(devices)
+		devices = append(devices, d)
+	}
+
+	return devices
+}
+
+// normalizeMAC returns the MAC address in lower case with colons, or an empty
+// string if it isn't a unicast MAC-48 address.
+func normalizeMAC(mac string) string {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil || len(hwAddr) != 6 || hwAddr[0]&0x01 != 0 || bytes.Equal(hwAddr, make(net.HardwareAddr, 6)) {
+		return ""
+	}
+	return hwAddr.String()
+}
+
+// lines returns the lines of the data without comments and blank lines.
+func lines(data []byte) []string {
+	var result []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// allLines reports whether every line of the data matches.
+func allLines(data []byte, match func(fields []string) bool) bool {
+	lines := lines(data)
+	return len(lines) > 0 && !slices.ContainsFunc(lines, func(line string) bool {
+		return !match(strings.Fields(line))
+	})
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"wakey/internal/config"
+)
+
+// Action is what an import does with a device.
+type Action string
+
+// Actions of the changes of an import.
+const (
+	ActionAdd    Action = "add"    // The device is new
+	ActionUpdate Action = "update" // The IP address of a device with the same MAC address is updated
+	ActionSkip   Action = "skip"   // A device with the same MAC address is left as it is
+)
+
+// Change is what an import does with an imported device.
+type Change struct {
+	Action   Action
+	Device   config.Device // The device as it is written to the config
+	Existing config.Device // The device with the same MAC address, if there is one
+}
+
+// Merge compares the imported devices with the devices in the config by MAC
+// address. New devices are added. Devices that are already in the config are
+// skipped, or have their IP address updated when update is true; their name,
+// description and settings are kept.
+func Merge(cfg config.Config, devices []config.Device, update bool) []Change {
+	// Index the devices in the config by MAC address
+	existing := make(map[string]config.Device)
+	for _, d := range cfg.Devices {
+		if mac := normalizeMAC(d.MacAddress); mac != "" {
+			existing[mac] = d
+		}
+	}
+
+	changes := make([]Change, 0, len(devices))
+	for _, d := range devices {
+		current, ok := existing[normalizeMAC(d.MacAddress)]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Action: ActionAdd, Device: d})
+		case update && d.IPAddress != "" && d.IPAddress != current.IPAddress:
+			updated := current
+			updated.IPAddress = d.IPAddress
+			changes = append(changes, Change{Action: ActionUpdate, Device: updated, Existing: current})
+		default:
+			changes = append(changes, Change{Action: ActionSkip, Device: current, Existing: current})
+		}
+	}
+
+	return changes
+}
+
+// Address describes where the device is reached, e.g. "192.168.1.10 ->
+// 192.168.1.20" for an update or "broadcast 192.168.1.255 port 9".
+func (c Change) Address() string {
+	address := c.Device.IPAddress
+	if c.Action == ActionUpdate {
+		address = c.Existing.IPAddress + " -> " + c.Device.IPAddress
+	}
+	if address == "" && c.Device.BroadcastAddress != "" {
+		address = "broadcast " + c.Device.BroadcastAddress
+	}
+	if c.Device.Port != 0 {
+		address += " port " + strconv.Itoa(c.Device.Port)
+	}
+	return address
+}
+
+// Apply returns the config with the changes applied. The config that is
+// passed in isn't modified.
+func Apply(cfg config.Config, changes []Change) config.Config {
+	cfg.Devices = slices.Clone(cfg.Devices)
+
+	for _, change := range changes {
+		switch change.Action {
+		case ActionAdd:
+			cfg.Devices = append(cfg.Devices, change.Device)
+		case ActionUpdate:
+			for i, d := range cfg.Devices {
+				if d.ID == change.Device.ID {
+					cfg.Devices[i] = change.Device
+				}
+			}
+		}
+	}
+
+	return cfg
+}
+
+// Count returns the number of changes with the action.
+func Count(changes []Change, action Action) int {
+	count := 0
+	for _, change := range changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary describes the changes, e.g. "2 to add, 1 to update, 3 to skip".
+func Summary(changes []Change) string {
+	return fmt.Sprintf("%d to add, %d to update, %d to skip",
+		Count(changes, ActionAdd), Count(changes, ActionUpdate), Count(changes, ActionSkip))
+}
+
+// Preview writes a line for every change so they can be checked before
+// they are written, e.g. "update  nas  00:11:32:aa:bb:cc  192.168.1.10 -> 192.168.1.20".
+func Preview(w io.Writer, changes []Change) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, change := range changes {
+		address := change.Address()
+		if change.Action == ActionSkip {
+			address += " (already in the config)"
+		}
+
+		fmt.Fprintln(tw, strings.Join([]string{string(change.Action), change.Device.DeviceName, change.Device.MacAddress, address}, "\t"))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"wakey/internal/common/wol"
+)
+
+// parseWakeonlan reads the host file of the wakeonlan Perl script, which has
+// a MAC address and optionally the IP address and port to send the magic
+// packet to on every line, e.g. "00:11:32:aa:bb:cc 192.168.1.255 9".
+func parseWakeonlan(data []byte) ([]Entry, error) {
+	var entries []Entry
+	for _, line := range lines(data) {
+		fields := strings.Fields(line)
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+
+		entry, err := wakeEntry(fields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseWol reads a file for wol -f, which has a MAC address and optionally
+// the host and port to send the magic packet to and a SecureOn password on
+// every line, e.g. "00:11:32:aa:bb:cc 192.168.1.255 9 01:02:03:04:05:06".
+func parseWol(data []byte) ([]Entry, error) {
+	var entries []Entry
+	for _, line := range lines(data) {
+		fields := strings.Fields(line)
+		if len(fields) > 4 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+
+		entry, err := wakeEntry(fields[:min(len(fields), 3)])
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %q: %v", line, err)
+		}
+
+		if len(fields) == 4 {
+			if _, err := wol.ParsePassword(fields[3]); err != nil {
+				return nil, fmt.Errorf("invalid line %q: invalid secureon password", line)
+			}
+			entry.SecureOn = fields[3]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// wakeEntry returns the entry for the MAC address, host and port the magic
+// packet of a device is sent to. The host is where the tools send the packet,
+// so it's kept as the broadcast address, whether it's a subnet-directed
+// broadcast or the address of the device.
+func wakeEntry(fields []string) (Entry, error) {
+	entry := Entry{MAC: fields[0]}
+
+	if len(fields) > 1 {
+		entry.BroadcastAddress = fields[1]
+	}
+
+	if len(fields) > 2 {
+		port, err := strconv.Atoi(fields[2])
+		if err != nil || port < 1 || port > 65535 {
+			return Entry{}, fmt.Errorf("invalid port %s", fields[2])
+		}
+		entry.Port = port
+	}
+
+	return entry, nil
+}
+
+// parseWakeMeOnLan reads the CSV or tab separated export of NirSoft
+// WakeMeOnLan. The columns are found by the names in the header, so the
+// columns can be in any order and columns that aren't needed are ignored.
+func parseWakeMeOnLan(data []byte) ([]Entry, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.Comma = csvDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header")
+	}
+
+	// Find the columns by name
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["mac address"]; !ok {
+		return nil, fmt.Errorf("no MAC Address column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entries []Entry
+	for _, record := range records[1:] {
+		entries = append(entries, Entry{
+			Name:        field(record, "computer name"),
+			Description: field(record, "network adapter company"),
+			MAC:         field(record, "mac address"),
+			IPAddress:   field(record, "ip address"),
+		})
+	}
+	return entries, nil
+}
+
+func detectWakeMeOnLan(data []byte) bool {
+	header, _, _ := strings.Cut(string(data), "\n")
+	header = strings.ToLower(header)
+	return strings.Contains(header, "mac address") && strings.Contains(header, "computer name")
+}
+
+// csvDelimiter returns the delimiter used in the header of the data, which
+// is a tab or semicolon in some exports instead of a comma.
+func csvDelimiter(data []byte) rune {
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	for _, delimiter := range []rune{'\t', ';'} {
+		if bytes.Count(header, []byte(string(delimiter))) > bytes.Count(header, []byte(",")) {
+			return delimiter
+		}
+	}
+	return ','
+}
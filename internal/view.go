@@ -2,6 +2,7 @@ package internal
 
 import (
 	"wakey/internal/common"
+	"wakey/internal/common/wol"
 	"wakey/internal/devices"
 	"wakey/internal/groups"
 
@@ -20,24 +21,40 @@ type Model struct {
 	CurrentView  View
 	CurrentModel tea.Model
 	Keys         common.KeyMap
+	Sender       wol.Sender // sends the magic packets of every view
 }
 
-func InitialModel() Model {
+func InitialModel(sender wol.Sender) Model {
 	return Model{
 		CurrentView:  DevicesView,
-		CurrentModel: devices.InitialModel(),
+		CurrentModel: devices.InitialModel(sender),
 		Keys:         common.DefaultKeyMap(),
+		Sender:       sender,
 	}
 }
 
-func (m *Model) SwitchView(view View) {
+// canceler is implemented by models that run work in the background which
+// has to stop when the model is left
+type canceler interface {
+	Cancel()
+}
+
+// SwitchView stops the current model and returns the command that starts the
+// new one
+func (m *Model) SwitchView(view View) tea.Cmd {
+	if c, ok := m.CurrentModel.(canceler); ok {
+		c.Cancel()
+	}
+
 	m.CurrentView = view
 	switch view {
 	case DevicesView:
-		m.CurrentModel = devices.InitialModel()
+		m.CurrentModel = devices.InitialModel(m.Sender)
 	case GroupsView:
-		m.CurrentModel = groups.InitialModel()
+		m.CurrentModel = groups.InitialModel(m.Sender)
 	}
+
+	return m.CurrentModel.Init()
 }
 
 func (m Model) Init() tea.Cmd {
@@ -49,13 +66,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.Keys.View):
+			var cmd tea.Cmd
 			switch m.CurrentView {
 			case DevicesView:
-				m.SwitchView(GroupsView)
+				cmd = m.SwitchView(GroupsView)
 			case GroupsView:
-				m.SwitchView(DevicesView)
+				cmd = m.SwitchView(DevicesView)
 			}
-			return m, tea.ClearScreen
+			return m, tea.Batch(tea.ClearScreen, cmd)
 		}
 	}
 
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"wakey/internal/common/wol"
+	"wakey/internal/config"
+)
+
+// listen prints every magic packet that reaches this machine, which helps to
+// find out whether wake packets make it to the subnet of a device.
+func listen(args []string) error {
+	flags := flag.NewFlagSet("listen", flag.ExitOnError)
+	portsFlag := flags.String("ports", "7,9", "comma separated UDP ports to listen on")
+	flags.Parse(args)
+
+	// Parse the ports
+	var ports []int
+	for _, value := range strings.Split(*portsFlag, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid port %s", value)
+		}
+		ports = append(ports, port)
+	}
+
+	// Create a map of MAC addresses to device names
+	deviceNames := make(map[string]string)
+	for _, device := range config.ReadConfig().Devices {
+		if hwAddr, err := net.ParseMAC(device.MacAddress); err == nil {
+			deviceNames[hwAddr.String()] = device.DeviceName
+		}
+	}
+
+	fmt.Printf("Listening for magic packets on UDP ports %s\n", *portsFlag)
+
+	return wol.Listen(ports, func(packet *wol.MagicPacket, from net.Addr) {
+		line := fmt.Sprintf("%s  from %s  target %s", time.Now().Format(time.TimeOnly), from, packet.HardwareAddr())
+
+		if name, ok := deviceNames[packet.HardwareAddr().String()]; ok {
+			line += fmt.Sprintf("  device [%s]", name)
+		} else {
+			line += "  unknown device"
+		}
+
+		if password := packet.Password(); password != nil {
+			line += fmt.Sprintf("  secureon %s", wol.FormatPassword(password))
+		}
+
+		fmt.Println(line)
+	})
+}
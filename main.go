@@ -6,15 +6,31 @@ import (
 
 	"wakey/internal"
 	"wakey/internal/common/status"
+	"wakey/internal/common/wol"
 	"wakey/internal/config"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	// Run the command if one was given instead of starting the TUI
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	status.Message = config.CreateConfig()
+
+	// Load the vendors of MAC addresses from a newer OUI file, if one is set
+	if err := config.ReadConfig().Settings.LoadOUIFile(); err != nil {
+		status.Message = err
+	}
+
 	// Create a new program and open the alternate screen
-	p := tea.NewProgram(internal.InitialModel(), tea.WithAltScreen())
+	p := tea.NewProgram(internal.InitialModel(wol.TransportSender{}), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v", err)
 		os.Exit(1)
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"wakey/internal/common/wol"
+)
+
+// relay wakes devices on this network for wakey instances on other networks,
+// e.g. clients connected over a VPN.
+func relay(args []string) error {
+	flags := flag.NewFlagSet("relay", flag.ExitOnError)
+	listenAddr := flags.String("listen", net.JoinHostPort("", strconv.Itoa(wol.DefaultRelayPort)), "TCP address to listen on")
+	iface := flags.String("interface", "", "interface or local address to send magic packets from")
+	flags.Parse(args)
+
+	// The secret is read from the environment so it doesn't show up in ps
+	secret := os.Getenv("WAKEY_RELAY_SECRET")
+	if secret == "" {
+		return fmt.Errorf("WAKEY_RELAY_SECRET must be set to the secret shared with the clients")
+	}
+
+	r := &wol.Relay{
+		Secret:    secret,
+		Interface: *iface,
+		Logf:      log.Printf,
+	}
+
+	log.Printf("Relaying wake requests on %s", *listenAddr)
+
+	return r.ListenAndServe(*listenAddr)
+}
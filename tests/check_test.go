@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wakey/internal/common/wol"
+)
+
+func TestProbeCheckTCP(t *testing.T) {
+	// Setup: Listen on a free local port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	check := wol.Check{Type: wol.ProbeTCP, Address: "127.0.0.1", Port: port, Timeout: time.Second}
+
+	// Verify: The open port is online
+	if result := wol.ProbeCheck(context.Background(), check); result.State != wol.StateOnline {
+		t.Errorf("Expected Online, got %s", result)
+	}
+
+	// Verify: The closed port is offline
+	listener.Close()
+	if result := wol.ProbeCheck(context.Background(), check); result.State != wol.StateOffline {
+		t.Errorf("Expected Offline, got %s", result)
+	}
+}
+
+func TestProbeCheckHTTP(t *testing.T) {
+	// Setup: Serve a health endpoint
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		check wol.Check
+		state wol.ProbeState
+	}{
+		{"Expected status", wol.Check{Type: wol.ProbeHTTP, URL: server.URL + "/health"}, wol.StateOnline},
+		{"Unexpected status", wol.Check{Type: wol.ProbeHTTP, URL: server.URL + "/"}, wol.StateError},
+		{"Custom status", wol.Check{Type: wol.ProbeHTTP, URL: server.URL + "/", Status: http.StatusNotFound}, wol.StateOnline},
+		{"Unknown type", wol.Check{Type: "smtp", Address: "127.0.0.1"}, wol.StateError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := wol.ProbeCheck(context.Background(), tt.check); result.State != tt.state {
+				t.Errorf("Expected %s, got %s", tt.state, result)
+			}
+		})
+	}
+}
+
+func TestProbeCheckHTTPSelfSigned(t *testing.T) {
+	// Setup: Serve a health endpoint with a self-signed certificate
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Execute: Probe the endpoint
+	result := wol.ProbeCheck(context.Background(), wol.Check{Type: wol.ProbeHTTP, URL: server.URL, Timeout: time.Second})
+
+	// Verify: The device answered, so it isn't offline and the cause is shown
+	if result.State != wol.StateError || !strings.Contains(result.String(), "certificate") {
+		t.Errorf("Expected an Error with the certificate problem, got %s", result)
+	}
+}
+
+func TestProbeCheckHTTPIPv6(t *testing.T) {
+	// Execute: Probe an IPv6 address without a port, nothing listens on port 80
+	result := wol.ProbeCheck(context.Background(), wol.Check{Type: wol.ProbeHTTP, Address: "::1", Timeout: time.Second})
+
+	// Verify: The URL is valid, so the request was sent and nothing answered
+	if result.State != wol.StateOffline {
+		t.Errorf("Expected Offline, got %s", result)
+	}
+}
+
+func TestProbeCheckHostname(t *testing.T) {
+	// Setup: Listen on a free local port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// Execute: Probe the device by its host name
+	check := wol.Check{Type: wol.ProbeTCP, Address: "localhost", Port: port, Timeout: time.Second}
+	result := wol.ProbeCheck(context.Background(), check)
+
+	// Verify: The probe reports the address the name resolved to
+	if result.State != wol.StateOnline {
+		t.Fatalf("Expected Online, got %s", result)
+	}
+
+	if !result.IP.IsLoopback() {
+		t.Errorf("Expected a loopback address, got %v", result.IP)
+	}
+}
+
+func TestIsMDNSName(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"gaming-pc.local", true},
+		{"Gaming-PC.LOCAL.", true},
+		{"nas.lan", false},
+		{"local", false},
+		{"192.168.1.10", false},
+	}
+
+	for _, tt := range tests {
+		if got := wol.IsMDNSName(tt.host); got != tt.want {
+			t.Errorf("IsMDNSName(%q) = %v, expected %v", tt.host, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package tests
 
 import (
+	"context"
+	"encoding/json"
+	"net"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
+	"wakey/internal/common/wol"
 	"wakey/internal/config"
 )
 
@@ -32,3 +38,137 @@ func TestReadConfig(t *testing.T) {
 		t.Errorf("Expected Device1, got %v", cfg.Devices)
 	}
 }
+
+func TestStreamUpdateState(t *testing.T) {
+	// Setup: Create a temporary config file with devices that can't be probed
+	tempFile, err := os.CreateTemp("", "config_test_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	cfg := config.Config{
+		Devices: []config.Device{
+			{ID: "1", DeviceName: "Device1", MacAddress: "00:00:00:00:00:01", IPAddress: "not an ip"},
+			{ID: "2", DeviceName: "Device2", MacAddress: "00:00:00:00:00:02", IPAddress: ""},
+			{ID: "3", DeviceName: "Device3", MacAddress: "00:00:00:00:00:03", IPAddress: "not an ip"},
+		},
+	}
+	if err := json.NewEncoder(tempFile).Encode(cfg); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Close()
+
+	// Override the ConfigPath to point to the temp file
+	config.ConfigPath = tempFile.Name()
+
+	// Execute: Stream the states of the devices
+	seen := make(map[string]int)
+	for update := range config.StreamUpdateState(context.Background()) {
+		seen[update.DeviceID]++
+	}
+
+	// Verify: Every device was probed once and its state was written
+	for _, device := range cfg.Devices {
+		if seen[device.ID] != 1 {
+			t.Errorf("Expected 1 update for device %s, got %d", device.ID, seen[device.ID])
+		}
+	}
+
+	for _, device := range config.ReadConfig().Devices {
+		if device.State == "" {
+			t.Errorf("Expected the state of device %s to be written", device.ID)
+		}
+	}
+}
+
+func TestStateUpdateApply(t *testing.T) {
+	device := config.Device{ID: "1", IPAddress: "192.168.1.5", State: "Offline"}
+
+	// Execute: Apply an update of a device that moved to a new IP address
+	update := config.StateUpdate{
+		DeviceID:   "1",
+		Result:     wol.ProbeResult{State: wol.StateOnline},
+		IPAddress:  "192.168.1.9",
+		PreviousIP: "192.168.1.5",
+	}
+	update.Apply(&device)
+
+	// Verify: The device has the new IP address and state
+	if device.IPAddress != "192.168.1.9" {
+		t.Errorf("Expected IP address 192.168.1.9, got %s", device.IPAddress)
+	}
+
+	if device.State != "Online" {
+		t.Errorf("Expected state Online, got %s", device.State)
+	}
+
+	// Verify: An update without a new IP address keeps the IP address
+	config.StateUpdate{DeviceID: "1", Result: wol.ProbeResult{State: wol.StateOffline}}.Apply(&device)
+	if device.IPAddress != "192.168.1.9" {
+		t.Errorf("Expected IP address 192.168.1.9 to be kept, got %s", device.IPAddress)
+	}
+}
+
+func TestTrackIP(t *testing.T) {
+	// Setup: Devices that track their IP address, the TCP probes fail fast
+	setupConfig(t, config.Config{Devices: []config.Device{
+		{ID: "1", DeviceName: "NAS", MacAddress: "aa:bb:cc:dd:ee:01", IPAddress: "192.0.2.10", TrackIP: true, ProbeType: "tcp", ProbePort: 9, ProbeTimeout: 50},
+		{ID: "2", DeviceName: "Desktop", MacAddress: "aa:bb:cc:dd:ee:02", IPAddress: "192.0.2.11", TrackIP: true, ProbeType: "tcp", ProbePort: 9, ProbeTimeout: 50},
+		{ID: "3", DeviceName: "Laptop", MacAddress: "aa:bb:cc:dd:ee:03", IPAddress: "192.0.2.12", TrackIP: true, ProbeType: "tcp", ProbePort: 9, ProbeTimeout: 50},
+	}})
+
+	// The NAS has a stale and a reachable entry, the desktop only a stale one
+	// and the laptop is asleep
+	stubNeighbors(t, func(ctx context.Context) ([]wol.Neighbor, error) {
+		return []wol.Neighbor{
+			{IP: net.ParseIP("192.0.2.20"), MAC: mustMAC(t, "aa:bb:cc:dd:ee:01"), State: wol.NeighborStale},
+			{IP: net.ParseIP("192.0.2.30"), MAC: mustMAC(t, "aa:bb:cc:dd:ee:01"), State: wol.NeighborReachable},
+			{IP: net.ParseIP("192.0.2.40"), MAC: mustMAC(t, "aa:bb:cc:dd:ee:02"), State: wol.NeighborStale},
+		}, nil
+	})
+
+	var sweeps atomic.Int32
+	original := config.TrackSweep
+	config.TrackSweep = func(ctx context.Context) error {
+		sweeps.Add(1)
+		return nil
+	}
+	t.Cleanup(func() { config.TrackSweep = original })
+
+	// Execute: Refresh twice
+	for range config.StreamUpdateState(context.Background()) {
+	}
+	first := sweeps.Load()
+	for range config.StreamUpdateState(context.Background()) {
+	}
+
+	// Verify: Reachable entries are preferred and stale ones are accepted
+	want := map[string]string{"1": "192.0.2.30", "2": "192.0.2.40", "3": "192.0.2.12"}
+	for _, device := range config.ReadConfig().Devices {
+		if device.IPAddress != want[device.ID] {
+			t.Errorf("Expected %s at %s, got %s", device.DeviceName, want[device.ID], device.IPAddress)
+		}
+	}
+
+	// Verify: The sleeping laptop doesn't make every refresh sweep
+	if first > 1 || sweeps.Load() != first {
+		t.Errorf("Expected at most one sweep within TrackSweepInterval, got %d", sweeps.Load())
+	}
+}
+
+func TestDeviceTargetRepeat(t *testing.T) {
+	settings := config.Settings{Repeat: 3, Interval: 50}
+
+	// Verify: The global repeat and interval are used when the device has none
+	target := config.Device{MacAddress: "00:11:22:33:44:55"}.Target(settings)
+	if target.Repeat != 3 || target.Interval != 50*time.Millisecond {
+		t.Errorf("Expected the global repeat and interval, got %d and %s", target.Repeat, target.Interval)
+	}
+
+	// Verify: The settings of the device override them
+	target = config.Device{MacAddress: "00:11:22:33:44:55", Repeat: 5, Interval: 10}.Target(settings)
+	if target.Repeat != 5 || target.Interval != 10*time.Millisecond {
+		t.Errorf("Expected the repeat and interval of the device, got %d and %s", target.Repeat, target.Interval)
+	}
+}
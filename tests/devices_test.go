@@ -0,0 +1,310 @@
+package tests
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"wakey/internal/common/refresh"
+	"wakey/internal/common/status"
+	"wakey/internal/common/wol"
+	"wakey/internal/config"
+	"wakey/internal/devices"
+	"wakey/internal/devices/device"
+	"wakey/internal/devices/discover"
+	"wakey/internal/devices/imports"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestEnterWakesSelectedDevice(t *testing.T) {
+	// Setup: Create a temporary config file with three devices
+	tempFile, err := os.CreateTemp("", "config_test_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	cfg := config.Config{
+		Devices: []config.Device{
+			{ID: "1", DeviceName: "Device1", MacAddress: "00:00:00:00:00:01", IPAddress: "127.0.0.1"},
+			{ID: "2", DeviceName: "Device2", MacAddress: "00:00:00:00:00:02", IPAddress: "127.0.0.1"},
+			{ID: "3", DeviceName: "Device3", MacAddress: "00:00:00:00:00:03", IPAddress: "127.0.0.1"},
+		},
+	}
+	if err := json.NewEncoder(tempFile).Encode(cfg); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Close()
+
+	// Override the ConfigPath to point to the temp file
+	config.ConfigPath = tempFile.Name()
+
+	// Execute: Move to the third row and press enter
+	recorder := &wol.Recorder{}
+	var m tea.Model = devices.InitialModel(recorder)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// Verify: The wake runs as a command instead of blocking Update
+	if len(recorder.Packets()) != 0 {
+		t.Fatalf("Expected the packet to be sent by the returned command")
+	}
+	startCmd(cmd)
+
+	// Verify: A single packet was sent for the third device
+	deadline := time.Now().Add(2 * time.Second)
+	for len(recorder.Packets()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	packets := recorder.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("Expected 1 packet, got %d", len(packets))
+	}
+
+	packet, err := wol.Parse(packets[0].Packet)
+	if err != nil {
+		t.Fatalf("Recorded packet is not a magic packet: %v", err)
+	}
+
+	if packet.HardwareAddr().String() != "00:00:00:00:00:03" {
+		t.Errorf("Expected packet for 00:00:00:00:00:03, got %s", packet.HardwareAddr())
+	}
+}
+
+// startCmd runs the command and the commands of a batch in the background,
+// like the Bubble Tea runtime does. Their messages are dropped.
+func startCmd(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	go func() {
+		if batch, ok := cmd().(tea.BatchMsg); ok {
+			for _, c := range batch {
+				startCmd(c)
+			}
+		}
+	}()
+}
+
+func TestWakeFinishesWhileFormIsOpen(t *testing.T) {
+	// Setup: A device whose TCP probe answers right away
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	setupConfig(t, config.Config{
+		Devices: []config.Device{
+			{ID: "1", DeviceName: "NAS", MacAddress: "00:11:32:aa:bb:cc", IPAddress: "127.0.0.1", ProbeType: "tcp", ProbePort: listener.Addr().(*net.TCPAddr).Port},
+		},
+		Settings: config.Settings{PollInterval: -1},
+	})
+	status.Message = nil
+
+	// Execute: Wake the device and open the form before the result arrives
+	var m tea.Model = devices.InitialModel(&wol.Recorder{})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'w'}})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	startCmd(cmd)
+
+	// The form drops the result, then the list is back
+	time.Sleep(100 * time.Millisecond)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	// Verify: The result is shown once the list handles a message
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(m.View(), "is online") && time.Now().Before(deadline) {
+		m, _ = m.Update(nil)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if view := m.View(); !strings.Contains(view, "[NAS] is online") || strings.Contains(view, "waking…") {
+		t.Errorf("Expected the wake to be finished, got %s", view)
+	}
+}
+
+func TestWakeWithoutDevices(t *testing.T) {
+	setupConfig(t, config.Config{})
+
+	// Verify: The keys that need a selected device don't panic without one
+	var m tea.Model = devices.InitialModel(&wol.Recorder{})
+	for _, r := range "wed" {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+func TestFormResumesPolling(t *testing.T) {
+	setupConfig(t, config.Config{})
+
+	// Execute: Open the form and go back to the list
+	var m tea.Model = devices.InitialModel(&wol.Recorder{})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	// Verify: The list gets a tick of its poller again
+	if cmd == nil {
+		t.Fatalf("Expected a command that restarts the polling")
+	}
+	if _, ok := cmd().(refresh.TickMsg); !ok {
+		t.Errorf("Expected a refresh.TickMsg")
+	}
+}
+
+func TestDeviceFormAcceptsBroadcastHostname(t *testing.T) {
+	// Setup: A device imported from a wol file, which sends to a host name
+	setupConfig(t, config.Config{Devices: []config.Device{
+		{ID: "1", DeviceName: "NAS", Description: "Storage", MacAddress: "00:11:32:aa:bb:cc", IPAddress: "192.168.1.10", BroadcastAddress: "nas.lan"},
+	}})
+
+	// Execute: Rename the device and submit the form
+	var m tea.Model = device.InitialModel(nil, []string{"1", "NAS", "Storage", "00:11:32:aa:bb:cc"})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	for range 18 {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// Verify: The device is saved with the host name
+	d := config.ReadConfig().Devices[0]
+	if d.DeviceName != "NAS2" || d.BroadcastAddress != "nas.lan" {
+		t.Errorf("Expected the device to be saved with the host name, got %+v", d)
+	}
+}
+
+func TestNewDevice(t *testing.T) {
+	a := config.NewDevice("NAS", "Storage", "00:11:22:33:44:55", "192.168.1.10")
+	b := config.NewDevice("NAS", "Storage", "00:11:22:33:44:55", "192.168.1.10")
+
+	// Verify: Every device gets its own ID and starts offline
+	if a.ID == "" || a.ID == b.ID {
+		t.Errorf("Expected unique IDs, got %q and %q", a.ID, b.ID)
+	}
+
+	if a.State != "Offline" {
+		t.Errorf("Expected state Offline, got %s", a.State)
+	}
+
+	if a.DeviceName != "NAS" || a.MacAddress != "00:11:22:33:44:55" || a.IPAddress != "192.168.1.10" {
+		t.Errorf("Unexpected device %+v", a)
+	}
+}
+
+// setupConfig writes the config to a file in a temporary directory and
+// points the ConfigPath to it
+func setupConfig(t *testing.T, cfg config.Config) {
+	t.Helper()
+
+	config.ConfigPath = t.TempDir() + "/config.json"
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	if err := os.WriteFile(config.ConfigPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func TestDiscoverRejectsInvalidNetwork(t *testing.T) {
+	tests := []struct {
+		network string
+		err     string
+	}{
+		{"192.168.1.0", "invalid network"},
+		{"10.0.0.0/20", "at most 1024 addresses"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			setupConfig(t, config.Config{})
+			var m tea.Model = discover.InitialModel(nil)
+
+			// Execute: Type the network and start the scan
+			for _, r := range tt.network {
+				m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+			m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+			// Verify: No scan was started and the error is shown
+			if cmd != nil {
+				t.Errorf("Expected no scan for %s", tt.network)
+			}
+
+			if !strings.Contains(m.View(), tt.err) {
+				t.Errorf("Expected %q in the view", tt.err)
+			}
+		})
+	}
+}
+
+func TestImportPreviewsBeforeWriting(t *testing.T) {
+	// Setup: Create an empty config and a file to import
+	setupConfig(t, config.Config{})
+
+	ethers := t.TempDir() + "/ethers"
+	if err := os.WriteFile(ethers, []byte("00:11:32:aa:bb:cc nas.lan\n"), 0644); err != nil {
+		t.Fatalf("Failed to write ethers: %v", err)
+	}
+
+	var m tea.Model = imports.InitialModel(nil)
+
+	// Execute: Type the file and preview it
+	for _, r := range ethers {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// Verify: The change is shown but not written yet
+	if !strings.Contains(m.View(), "1 to add") {
+		t.Errorf("Expected the preview to add a device, got:\n%s", m.View())
+	}
+	if len(config.ReadConfig().Devices) != 0 {
+		t.Fatalf("Expected nothing to be written before the import is confirmed")
+	}
+
+	// Execute: Confirm the import
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// Verify: The device has been written
+	devices := config.ReadConfig().Devices
+	if len(devices) != 1 || devices[0].DeviceName != "nas" || devices[0].IPAddress != "nas.lan" {
+		t.Errorf("Expected the imported device, got %+v", devices)
+	}
+}
+
+func TestDeviceFormKeepsRefreshedStates(t *testing.T) {
+	// Setup: A config with a single device
+	setupConfig(t, config.Config{Devices: []config.Device{
+		{ID: "1", DeviceName: "NAS", Description: "Storage", MacAddress: "00:11:32:aa:bb:cc", IPAddress: "192.168.1.10", State: "Offline"},
+	}})
+
+	// Execute: Open the form to edit the device
+	var m tea.Model = device.InitialModel(nil, []string{"1", "NAS", "Storage", "00:11:32:aa:bb:cc"})
+
+	// A refresh records the state and a tracked IP address while the form is open
+	cfg := config.ReadConfig()
+	cfg.Devices[0].State = "Online"
+	cfg.Devices[0].RTT = "1ms"
+	cfg.Devices[0].IPAddress = "192.168.1.20"
+	config.WriteConfig(cfg)
+
+	// Rename the device and submit the form
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	for range 18 {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	// Verify: The rename is saved without losing the refreshed values
+	d := config.ReadConfig().Devices[0]
+	if d.DeviceName != "NAS2" {
+		t.Fatalf("Expected the device to be renamed, got %+v", d)
+	}
+	if d.State != "Online" || d.RTT != "1ms" || d.IPAddress != "192.168.1.20" {
+		t.Errorf("Expected the refreshed state and IP address to be kept, got %+v", d)
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+	"wakey/internal/common/wol"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsResponse packs an mDNS response with the records as answers.
+func mdnsResponse(t *testing.T, response bool, answers ...dnsmessage.Resource) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{Header: dnsmessage.Header{Response: response}, Answers: answers}
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("Failed to pack the response: %v", err)
+	}
+	return data
+}
+
+// record returns a resource with the name and body.
+func record(name string, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET},
+		Body:   body,
+	}
+}
+
+// ptr returns a PTR record that points the service type to an instance.
+func ptr(name, instance string) dnsmessage.Resource {
+	return record(name, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(instance)})
+}
+
+// srv returns an SRV record with the host and port of an instance.
+func srv(instance, host string, port uint16) dnsmessage.Resource {
+	return record(instance, &dnsmessage.SRVResource{Target: dnsmessage.MustNewName(host), Port: port})
+}
+
+// aRecord returns an A record with the IPv4 address of a host.
+func aRecord(host, ip string) dnsmessage.Resource {
+	return record(host, &dnsmessage.AResource{A: [4]byte(net.ParseIP(ip).To4())})
+}
+
+// browseResponses are the answers of a NAS with two services, a Linux desktop
+// announced by Avahi and a printer without an address.
+func browseResponses(t *testing.T) [][]byte {
+	return [][]byte{
+		mdnsResponse(t, true,
+			ptr("_smb._tcp.local.", "Office NAS._smb._tcp.local."),
+			srv("office nas._smb._tcp.local.", "NAS.local.", 445), // DNS names compare without case
+			ptr("_ssh._tcp.local.", "nas._ssh._tcp.local."),
+			srv("nas._ssh._tcp.local.", "nas.local.", 22),
+			aRecord("nas.local.", "192.168.1.10"),
+		),
+		mdnsResponse(t, true,
+			ptr("_workstation._tcp.local.", "desktop [00:1b:21:01:02:03]._workstation._tcp.local."),
+			srv("desktop [00:1b:21:01:02:03]._workstation._tcp.local.", "desktop.local.", 9),
+			aRecord("desktop.local.", "192.168.1.5"),
+		),
+		mdnsResponse(t, true,
+			ptr("_ipp._tcp.local.", "Printer._ipp._tcp.local."),
+			srv("Printer._ipp._tcp.local.", "printer.local.", 631),
+		),
+		// Queries of other hosts and garbage are skipped
+		mdnsResponse(t, false, ptr("_smb._tcp.local.", "Query._smb._tcp.local.")),
+		[]byte("not a dns message"),
+	}
+}
+
+func TestParseServices(t *testing.T) {
+	services := wol.ParseServices(browseResponses(t), "_smb._tcp", "_ssh._tcp", "_workstation._tcp", "_ipp._tcp")
+
+	want := []wol.Service{
+		{Instance: "Office NAS", Type: "_smb._tcp", Host: "nas.local", IP: net.ParseIP("192.168.1.10"), Port: 445},
+		{Instance: "nas", Type: "_ssh._tcp", Host: "nas.local", IP: net.ParseIP("192.168.1.10"), Port: 22},
+		{Instance: "desktop [00:1b:21:01:02:03]", Type: "_workstation._tcp", Host: "desktop.local", IP: net.ParseIP("192.168.1.5"), Port: 9},
+		{Instance: "Printer", Type: "_ipp._tcp", Host: "printer.local", Port: 631},
+	}
+
+	if len(services) != len(want) {
+		t.Fatalf("Expected %d services, got %d: %+v", len(want), len(services), services)
+	}
+	for i, service := range services {
+		w := want[i]
+		if service.Instance != w.Instance || service.Type != w.Type || service.Host != w.Host || !service.IP.Equal(w.IP) || service.Port != w.Port {
+			t.Errorf("Expected %+v, got %+v", w, service)
+		}
+	}
+}
+
+func TestMergeServices(t *testing.T) {
+	services := wol.ParseServices(browseResponses(t), "_smb._tcp", "_ssh._tcp", "_workstation._tcp", "_ipp._tcp")
+	neighbors := []wol.Neighbor{
+		{IP: net.ParseIP("192.168.1.10"), MAC: mustMAC(t, "00:11:32:aa:bb:cc"), State: wol.NeighborReachable},
+	}
+
+	discovered := wol.MergeServices(services, neighbors)
+
+	// Verify: One device per address sorted by address, the printer has none
+	if len(discovered) != 2 {
+		t.Fatalf("Expected 2 devices, got %d: %+v", len(discovered), discovered)
+	}
+
+	// The MAC address of the desktop is read from its workstation name
+	desktop := discovered[0]
+	if !desktop.IP.Equal(net.ParseIP("192.168.1.5")) || desktop.Name != "desktop" || desktop.MAC.String() != "00:1b:21:01:02:03" {
+		t.Errorf("Expected the desktop with the MAC address of its name, got %+v", desktop)
+	}
+
+	// The services of the NAS are merged and its MAC address is looked up
+	nas := discovered[1]
+	if nas.Name != "Office NAS" || nas.Hostname != "nas.local" || nas.MAC.String() != "00:11:32:aa:bb:cc" {
+		t.Errorf("Expected the NAS with the MAC address of the neighbor, got %+v", nas)
+	}
+	if !slices.Equal(nas.Services, []string{"_smb._tcp", "_ssh._tcp"}) {
+		t.Errorf("Expected the smb and ssh services, got %v", nas.Services)
+	}
+}
+
+func TestBrowseDeadline(t *testing.T) {
+	// Passing the deadline ends the browse without an error
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := wol.Browse(ctx); err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Skipf("Can't send the mDNS query: %v", err)
+		}
+		t.Errorf("Expected no error when the deadline passes, got %v", err)
+	}
+
+	// Cancelling the browse returns the error
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := wol.Browse(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wakey/internal/common/status"
+	"wakey/internal/common/wol"
+	"wakey/internal/config"
+	"wakey/internal/groups"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestWakeGroupReportsDeletedDevices(t *testing.T) {
+	// Setup: A group with three devices, one of them has been deleted
+	setupConfig(t, config.Config{
+		Devices: []config.Device{
+			{ID: "1", DeviceName: "NAS", MacAddress: "00:11:32:aa:bb:cc"},
+			{ID: "2", DeviceName: "Desktop", MacAddress: "00:1b:21:01:02:03"},
+		},
+		Groups: []config.Group{
+			{ID: "g", GroupName: "Office", Devices: []string{"1", "2", "3"}},
+		},
+	})
+	status.Message = nil
+
+	// Execute: Wake the group and hand the results back to the model
+	recorder := &wol.Recorder{}
+	var m tea.Model = groups.InitialModel(recorder)
+	m, _ = m.Update(nil)
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	for _, msg := range runCmd(cmd) {
+		m, _ = m.Update(msg)
+	}
+
+	// Verify: The deleted device is reported as failed
+	if len(recorder.Packets()) != 2 {
+		t.Errorf("Expected 2 packets, got %d", len(recorder.Packets()))
+	}
+	if status.Message == nil || !strings.Contains(status.Message.Error(), "woke 2/3, failed: 3 (device not found)") {
+		t.Errorf("Expected the deleted device in the status, got %v", status.Message)
+	}
+}
+
+func TestGroupKeysWithoutGroups(t *testing.T) {
+	setupConfig(t, config.Config{})
+
+	// Verify: The keys that need a selected group don't panic without one
+	var m tea.Model = groups.InitialModel(&wol.Recorder{})
+	for _, r := range "ed" {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+// runCmd runs the command and the commands of a batch, like the Bubble Tea
+// runtime does, and returns their messages. Commands that take longer than
+// two seconds are left running.
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	msgs := make(chan tea.Msg)
+	done := make(chan struct{})
+	defer close(done)
+	var pending int
+
+	var start func(cmd tea.Cmd)
+	start = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		pending++
+		go func() {
+			select {
+			case msgs <- cmd():
+			case <-done:
+			}
+		}()
+	}
+	start(cmd)
+
+	var result []tea.Msg
+	timeout := time.After(2 * time.Second)
+	for pending > 0 {
+		select {
+		case msg := <-msgs:
+			pending--
+			if batch, ok := msg.(tea.BatchMsg); ok {
+				for _, c := range batch {
+					start(c)
+				}
+			} else if msg != nil {
+				result = append(result, msg)
+			}
+		case <-timeout:
+			return result
+		}
+	}
+	return result
+}
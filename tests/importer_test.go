@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"wakey/internal/config"
+	"wakey/internal/importer"
+)
+
+// importedDevice is the part of an imported device that the tests compare
+type importedDevice struct {
+	name, mac, ip string
+}
+
+func TestImporterRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   []importedDevice
+	}{
+		{
+			"ethers",
+			"ethers",
+			"# comment\n" +
+				"00:11:32:AA:BB:CC nas.lan\n" +
+				"b8:27:eb:01:02:03 192.168.1.30\n",
+			[]importedDevice{
+				{"nas", "00:11:32:aa:bb:cc", "nas.lan"},
+				{"192.168.1.30", "b8:27:eb:01:02:03", "192.168.1.30"},
+			},
+		},
+		{
+			"dnsmasq",
+			"dnsmasq",
+			"1717171717 00:11:32:aa:bb:cc 192.168.1.10 nas 01:00:11:32:aa:bb:cc\n" +
+				"1717171718 b8:27:eb:01:02:03 192.168.1.30 * *\n" +
+				"duid 00:01:00:01:2c:5a:2b:1c:00:11:32:aa:bb:cc\n" +
+				"1717171719 1234567 fd00::10 nas *\n",
+			[]importedDevice{
+				{"nas", "00:11:32:aa:bb:cc", "192.168.1.10"},
+				{"192.168.1.30", "b8:27:eb:01:02:03", "192.168.1.30"},
+			},
+		},
+		{
+			"dhcpd",
+			"dhcpd",
+			"# The format of this file is documented in the dhcpd.leases(5) manual page.\n" +
+				"lease 192.168.1.10 {\n" +
+				"  starts 4 2024/05/30 10:00:00;\n" +
+				"  binding state active;\n" +
+				"  hardware ethernet 00:11:32:aa:bb:cc;\n" +
+				"  client-hostname \"nas\";\n" +
+				"}\n" +
+				"lease 192.168.1.11 {\n" +
+				"  binding state active;\n" +
+				"  hardware ethernet 00:11:32:aa:bb:cc;\n" +
+				"  client-hostname \"nas\";\n" +
+				"}\n",
+			[]importedDevice{
+				{"nas", "00:11:32:aa:bb:cc", "192.168.1.11"},
+			},
+		},
+		{
+			"ip neigh",
+			"neigh",
+			"fe80::1 dev eth0 lladdr 00:11:32:aa:bb:cc STALE\n" +
+				"192.168.1.10 dev eth0 lladdr 00:11:32:aa:bb:cc REACHABLE\n" +
+				"192.168.1.40 dev eth0 FAILED\n",
+			[]importedDevice{
+				{"192.168.1.10", "00:11:32:aa:bb:cc", "192.168.1.10"},
+			},
+		},
+		{
+			"detected arp -a",
+			"auto",
+			"? (192.168.1.10) at 0:11:32:aa:bb:cc on en0 ifscope [ethernet]\n" +
+				"? (224.0.0.251) at 1:0:5e:0:0:fb on en0 ifscope permanent [ethernet]\n",
+			[]importedDevice{
+				{"192.168.1.10", "00:11:32:aa:bb:cc", "192.168.1.10"},
+			},
+		},
+		{
+			"wakeonlan",
+			"wakeonlan",
+			"# wakeonlan -f hosts\n" +
+				"00:11:32:aa:bb:cc 192.168.1.255 9\n" +
+				"b8-27-eb-01-02-03 192.168.1.30\n" +
+				"00:1b:21:01:02:03\n",
+			[]importedDevice{
+				{"00:11:32:aa:bb:cc", "00:11:32:aa:bb:cc", ""},
+				{"b8:27:eb:01:02:03", "b8:27:eb:01:02:03", ""},
+				{"00:1b:21:01:02:03", "00:1b:21:01:02:03", ""},
+			},
+		},
+		{
+			"wol",
+			"wol",
+			"00:11:32:aa:bb:cc nas.lan 9 01:02:03:04:05:06\n",
+			[]importedDevice{
+				{"00:11:32:aa:bb:cc", "00:11:32:aa:bb:cc", ""},
+			},
+		},
+		{
+			"detected WakeMeOnLan CSV",
+			"auto",
+			"\xef\xbb\xbfIP Address,Computer Name,MAC Address,Workgroup,Network Adapter Company\n" +
+				"192.168.1.10,NAS,00-11-32-AA-BB-CC,WORKGROUP,Synology Incorporated\n" +
+				"192.168.1.20,PRINTER,,WORKGROUP,\n",
+			[]importedDevice{
+				{"NAS", "00:11:32:aa:bb:cc", "192.168.1.10"},
+			},
+		},
+		{
+			"detected WakeMeOnLan tab separated",
+			"auto",
+			"Computer Name\tMAC Address\tIP Address\n" +
+				"DESKTOP\t00:1B:21:01:02:03\t192.168.1.50\n",
+			[]importedDevice{
+				{"DESKTOP", "00:1b:21:01:02:03", "192.168.1.50"},
+			},
+		},
+		{
+			"detected dnsmasq",
+			"",
+			"1717171717 00:11:32:aa:bb:cc 192.168.1.10 nas *\n",
+			[]importedDevice{
+				{"nas", "00:11:32:aa:bb:cc", "192.168.1.10"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			devices, err := importer.Read(strings.NewReader(test.data), test.format)
+			if err != nil {
+				t.Fatalf("Read returned an error: %v", err)
+			}
+
+			if len(devices) != len(test.want) {
+				t.Fatalf("Read returned %d devices, want %d: %+v", len(devices), len(test.want), devices)
+			}
+
+			for i, d := range devices {
+				got := importedDevice{d.DeviceName, d.MacAddress, d.IPAddress}
+				if got != test.want[i] {
+					t.Errorf("device %d = %+v, want %+v", i, got, test.want[i])
+				}
+				if d.ID == "" || d.Description == "" {
+					t.Errorf("device %d has no ID or description: %+v", i, d)
+				}
+			}
+		})
+	}
+}
+
+func TestImporterReadErrors(t *testing.T) {
+	if _, err := importer.Read(strings.NewReader("lease 192.168.1.10 {\n"), "dhcpd"); err == nil {
+		t.Error("Read accepted a lease that isn't closed")
+	}
+
+	if _, err := importer.Read(strings.NewReader("not a known format\n"), "auto"); err == nil {
+		t.Error("Read detected a format for unknown data")
+	}
+
+	if _, err := importer.Read(strings.NewReader("00:11:32:aa:bb:cc 192.168.1.255 port\n"), "wakeonlan"); err == nil {
+		t.Error("Read accepted an invalid port")
+	}
+
+	if _, err := importer.Read(strings.NewReader("00:11:32:aa:bb:cc host 9 secret\n"), "wol"); err == nil {
+		t.Error("Read accepted an invalid SecureOn password")
+	}
+
+	if _, err := importer.Read(strings.NewReader(""), "csv"); err == nil {
+		t.Error("Read accepted an unknown format")
+	}
+}
+
+func TestImporterReadWakeSettings(t *testing.T) {
+	devices, err := importer.Read(strings.NewReader("00:11:32:aa:bb:cc 192.168.1.255 7 01:02:03:04:05:06\n"), "wol")
+	if err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+
+	d := devices[0]
+	if d.BroadcastAddress != "192.168.1.255" || d.Port != 7 || d.SecureOn != "01:02:03:04:05:06" {
+		t.Errorf("device = %+v, want the broadcast address, port and password", d)
+	}
+
+	// Broadcast addresses of smaller subnets don't end in .255
+	devices, err = importer.Read(strings.NewReader("00:11:32:aa:bb:cc 10.0.0.127\n"), "wakeonlan")
+	if err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+
+	if d := devices[0]; d.BroadcastAddress != "10.0.0.127" || d.IPAddress != "" {
+		t.Errorf("device = %+v, want the address as the broadcast address", d)
+	}
+}
+
+func TestImporterMerge(t *testing.T) {
+	cfg := config.Config{Devices: []config.Device{
+		{ID: "1", DeviceName: "My NAS", MacAddress: "00:11:32:AA:BB:CC", IPAddress: "192.168.1.10"},
+		{ID: "2", DeviceName: "Pi", MacAddress: "b8:27:eb:01:02:03", IPAddress: "192.168.1.30"},
+	}}
+
+	imported := []config.Device{
+		{ID: "a", DeviceName: "nas", MacAddress: "00:11:32:aa:bb:cc", IPAddress: "192.168.1.11"},
+		{ID: "b", DeviceName: "pi", MacAddress: "b8:27:eb:01:02:03", IPAddress: "192.168.1.30"},
+		{ID: "c", DeviceName: "desktop", MacAddress: "00:1b:21:01:02:03", IPAddress: "192.168.1.50"},
+	}
+
+	// Duplicates are skipped by default
+	changes := importer.Merge(cfg, imported, false)
+	if got := importer.Summary(changes); got != "1 to add, 0 to update, 2 to skip" {
+		t.Errorf("Summary = %q", got)
+	}
+
+	// Duplicates with a new IP address are updated with update
+	changes = importer.Merge(cfg, imported, true)
+	if got := importer.Summary(changes); got != "1 to add, 1 to update, 1 to skip" {
+		t.Errorf("Summary = %q", got)
+	}
+
+	var preview strings.Builder
+	if err := importer.Preview(&preview, changes); err != nil {
+		t.Fatalf("Preview returned an error: %v", err)
+	}
+	if !strings.Contains(preview.String(), "192.168.1.10 -> 192.168.1.11") {
+		t.Errorf("Preview doesn't show the new IP address:\n%s", preview.String())
+	}
+
+	merged := importer.Apply(cfg, changes)
+	if len(merged.Devices) != 3 {
+		t.Fatalf("Apply returned %d devices, want 3", len(merged.Devices))
+	}
+	if d := merged.Devices[0]; d.DeviceName != "My NAS" || d.IPAddress != "192.168.1.11" {
+		t.Errorf("updated device = %+v, want the name kept and the IP address updated", d)
+	}
+	if merged.Devices[2].ID != "c" {
+		t.Errorf("added device = %+v", merged.Devices[2])
+	}
+
+	// The config that was passed in is left as it is
+	if cfg.Devices[0].IPAddress != "192.168.1.10" {
+		t.Error("Apply modified the config that was passed in")
+	}
+}
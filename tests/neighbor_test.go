@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"wakey/internal/common/wol"
+)
+
+func TestParseNeighbors(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		want  []wol.Neighbor
+	}{
+		{
+			"ip neigh",
+			"192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:01 REACHABLE\n" +
+				"192.168.1.20 dev eth0 lladdr aa:bb:cc:dd:ee:02 STALE\n" +
+				"192.168.1.30 dev eth0 FAILED\n" +
+				"fe80::1 dev eth0 lladdr aa:bb:cc:dd:ee:01 router DELAY\n",
+			[]wol.Neighbor{
+				{IP: net.ParseIP("192.168.1.1"), MAC: mustMAC(t, "aa:bb:cc:dd:ee:01"), Interface: "eth0", State: wol.NeighborReachable},
+				{IP: net.ParseIP("192.168.1.20"), MAC: mustMAC(t, "aa:bb:cc:dd:ee:02"), Interface: "eth0", State: wol.NeighborStale},
+				{IP: net.ParseIP("fe80::1"), MAC: mustMAC(t, "aa:bb:cc:dd:ee:01"), Interface: "eth0", State: wol.NeighborDelay},
+			},
+		},
+		{
+			"arp -an on macOS",
+			"? (192.168.1.1) at 0:1b:2c:3:4:5 on en0 ifscope [ethernet]\n" +
+				"? (192.168.1.30) at (incomplete) on en0 ifscope [ethernet]\n" +
+				"? (224.0.0.251) at 1:0:5e:0:0:fb on en0 ifscope permanent [ethernet]\n",
+			[]wol.Neighbor{
+				{IP: net.ParseIP("192.168.1.1"), MAC: mustMAC(t, "00:1b:2c:03:04:05"), Interface: "en0", State: wol.NeighborReachable},
+				{IP: net.ParseIP("224.0.0.251"), MAC: mustMAC(t, "01:00:5e:00:00:fb"), Interface: "en0", State: wol.NeighborPermanent},
+			},
+		},
+		{
+			"arp -a on Windows",
+			"\r\nInterface: 192.168.1.10 --- 0xb\r\n" +
+				"  Internet Address      Physical Address      Type\r\n" +
+				"  192.168.1.1           aa-bb-cc-dd-ee-01     dynamic\r\n" +
+				"  192.168.1.255         ff-ff-ff-ff-ff-ff     static\r\n",
+			[]wol.Neighbor{
+				{IP: net.ParseIP("192.168.1.1"), MAC: mustMAC(t, "aa:bb:cc:dd:ee:01"), State: wol.NeighborReachable},
+				{IP: net.ParseIP("192.168.1.255"), MAC: mustMAC(t, "ff:ff:ff:ff:ff:ff"), State: wol.NeighborPermanent},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wol.ParseNeighbors(strings.NewReader(tt.table))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d neighbors, got %d: %v", len(tt.want), len(got), got)
+			}
+
+			for i, want := range tt.want {
+				if !got[i].IP.Equal(want.IP) || got[i].MAC.String() != want.MAC.String() || got[i].Interface != want.Interface || got[i].State != want.State {
+					t.Errorf("Expected %v, got %v", want, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFindNeighbor(t *testing.T) {
+	mac := mustMAC(t, "aa:bb:cc:dd:ee:01")
+	neighbors := []wol.Neighbor{
+		{IP: net.ParseIP("192.168.1.5"), MAC: mac, State: wol.NeighborStale},
+		{IP: net.ParseIP("192.168.1.9"), MAC: mac, State: wol.NeighborReachable},
+	}
+
+	// A reachable entry is preferred over a stale one
+	neighbor, ok := wol.FindNeighbor(neighbors, mac)
+	if !ok || !neighbor.IP.Equal(net.ParseIP("192.168.1.9")) {
+		t.Errorf("Expected 192.168.1.9, got %v", neighbor.IP)
+	}
+
+	if _, ok := wol.FindNeighbor(neighbors, mustMAC(t, "aa:bb:cc:dd:ee:02")); ok {
+		t.Errorf("Expected no neighbor for an unknown MAC address")
+	}
+}
+
+func TestProbeNeighborConfirmsNewAddress(t *testing.T) {
+	// Setup: Listen where the device moved to, the discard port needs root
+	conn, err := net.ListenPacket("udp4", "127.0.0.2:9")
+	if err != nil {
+		t.Skipf("Can't listen on the discard port: %v", err)
+	}
+	defer conn.Close()
+
+	confirmed := make(chan struct{})
+	go func() {
+		if _, _, err := conn.ReadFrom(make([]byte, 1)); err == nil {
+			close(confirmed)
+		}
+	}()
+
+	// The entry at the new address only becomes reachable once it is touched,
+	// like the kernel confirms a stale neighbor
+	mac := mustMAC(t, "aa:bb:cc:dd:ee:01")
+	stubNeighbors(t, func(ctx context.Context) ([]wol.Neighbor, error) {
+		state := wol.NeighborStale
+		select {
+		case <-confirmed:
+			state = wol.NeighborReachable
+		default:
+		}
+		return []wol.Neighbor{{IP: net.ParseIP("127.0.0.2"), MAC: mac, State: state}}, nil
+	})
+
+	// Execute: Probe the device at its old address
+	check := wol.Check{Type: wol.ProbeARP, Address: "127.0.0.1", MAC: mac.String(), Timeout: 2 * time.Second}
+	result := wol.ProbeCheck(context.Background(), check)
+
+	// Verify: The new address was confirmed and reported
+	if result.State != wol.StateOnline || !result.IP.Equal(net.ParseIP("127.0.0.2")) {
+		t.Errorf("Expected Online at 127.0.0.2, got %s at %v", result, result.IP)
+	}
+}
+
+// stubNeighbors replaces the neighbor table for the test.
+func stubNeighbors(t *testing.T, table func(ctx context.Context) ([]wol.Neighbor, error)) {
+	t.Helper()
+	original := wol.NeighborTable
+	wol.NeighborTable = table
+	t.Cleanup(func() { wol.NeighborTable = original })
+}
+
+// mustMAC parses the MAC address or fails the test.
+func mustMAC(t *testing.T, mac string) net.HardwareAddr {
+	t.Helper()
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		t.Fatalf("Invalid MAC address %s: %v", mac, err)
+	}
+	return hwAddr
+}
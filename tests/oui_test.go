@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"wakey/internal/common/wol"
+)
+
+func TestVendor(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"00:11:32:aa:bb:cc", "Synology Incorporated"},
+		{"B8-27-EB-01-02-03", "Raspberry Pi Foundation"},
+		{"dc:a6:32:01:02:03", "Raspberry Pi Trading Ltd"},
+		{"02:11:32:aa:bb:cc", ""}, // locally administered
+		{"not a mac", ""},
+	}
+
+	for _, test := range tests {
+		if got := wol.Vendor(test.mac); got != test.want {
+			t.Errorf("Vendor(%q) = %q, want %q", test.mac, got, test.want)
+		}
+	}
+}
+
+func TestLoadOUI(t *testing.T) {
+	data := "OUI/MA-L                                                    Organization\n" +
+		"\n" +
+		"70-B3-D5   (hex)\t\tIEEE Registration Authority\n" +
+		"70B3D5     (base 16)\t\tIEEE Registration Authority\n" +
+		"\t\t\t\t445 Hoes Lane\n"
+
+	if err := wol.LoadOUI(strings.NewReader(data)); err != nil {
+		t.Fatalf("LoadOUI returned an error: %v", err)
+	}
+
+	if got := wol.Vendor("70:b3:d5:00:00:01"); got != "IEEE Registration Authority" {
+		t.Errorf("Vendor = %q, want the loaded vendor", got)
+	}
+
+	// The embedded vendors are kept
+	if got := wol.Vendor("00:11:32:aa:bb:cc"); got != "Synology Incorporated" {
+		t.Errorf("Vendor = %q, want the embedded vendor", got)
+	}
+
+	if err := wol.LoadOUI(strings.NewReader("no assignments\n")); err == nil {
+		t.Error("LoadOUI accepted a file without assignments")
+	}
+}
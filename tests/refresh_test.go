@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"wakey/internal/common/refresh"
+	"wakey/internal/config"
+)
+
+func TestPoller(t *testing.T) {
+	// Setup: Create a temporary config file with devices that can't be probed
+	tempFile, err := os.CreateTemp("", "config_test_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	cfg := config.Config{
+		Devices: []config.Device{
+			{ID: "1", DeviceName: "Device1", MacAddress: "00:00:00:00:00:01", IPAddress: ""},
+			{ID: "2", DeviceName: "Device2", MacAddress: "00:00:00:00:00:02", IPAddress: ""},
+		},
+	}
+	if err := json.NewEncoder(tempFile).Encode(cfg); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Close()
+
+	// Override the ConfigPath to point to the temp file
+	config.ConfigPath = tempFile.Name()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	poller := refresh.NewPoller(ctx, time.Minute)
+
+	if status := poller.Status(); status != "not checked yet" {
+		t.Errorf("Expected not checked yet, got %q", status)
+	}
+
+	// Execute: Run a refresh and follow the messages until it is done
+	cmd := poller.Refresh()
+	if poller.Refresh() != nil {
+		t.Errorf("Expected no second refresh while one is running")
+	}
+
+	updates := 0
+	for msg := cmd(); ; {
+		if _, ok := msg.(refresh.DoneMsg); ok {
+			break
+		}
+
+		update, ok := msg.(refresh.Msg)
+		if !ok {
+			t.Fatalf("Expected a refresh.Msg, got %T", msg)
+		}
+		updates++
+		msg = update.Next()()
+	}
+
+	// Verify: Every device was reported and the refresh was recorded
+	if updates != len(cfg.Devices) {
+		t.Errorf("Expected %d updates, got %d", len(cfg.Devices), updates)
+	}
+
+	if status := poller.Status(); !strings.HasPrefix(status, "last checked") {
+		t.Errorf("Expected last checked, got %q", status)
+	}
+
+	// Verify: Pausing is shown in the status
+	if !poller.TogglePause() || !strings.HasSuffix(poller.Status(), "(paused)") {
+		t.Errorf("Expected the poller to be paused, got %q", poller.Status())
+	}
+}
+
+func TestPollerResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	poller := refresh.NewPoller(ctx, time.Minute)
+	poller.TogglePause()
+
+	// Setup: The first tick is lost while a form has the messages
+	lost := poller.Update(nil)
+	if lost == nil {
+		t.Fatalf("Expected the ticks to start")
+	}
+
+	// Execute: Restart the ticks when the list is back
+	resumed := poller.Resume()
+
+	// Verify: The lost tick is dropped and the new one keeps ticking
+	if poller.Update(lost()) != nil {
+		t.Errorf("Expected the tick before the restart to be dropped")
+	}
+	if poller.Update(resumed()) == nil {
+		t.Errorf("Expected the restarted tick to send the next one")
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"net"
+	"testing"
+	"time"
+	"wakey/internal/common/wol"
+)
+
+func TestRelay(t *testing.T) {
+	// Receive the magic packet the relay sends on its network
+	receiver, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer receiver.Close()
+
+	// Start the relay
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	relay := &wol.Relay{Secret: "secret"}
+	go relay.Serve(listener)
+
+	target := wol.Target{
+		MacAddress:       "00:11:22:33:44:55",
+		BroadcastAddress: "127.0.0.1",
+		Port:             receiver.LocalAddr().(*net.UDPAddr).Port,
+		Relay:            listener.Addr().String(),
+		RelaySecret:      "secret",
+	}
+
+	if err := wol.WakeDevice(wol.TransportSender{}, target); err != nil {
+		t.Fatalf("WakeDevice returned error: %v", err)
+	}
+
+	receiver.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1500)
+	n, _, err := receiver.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("No magic packet received: %v", err)
+	}
+
+	packet, err := wol.Parse(buf[:n])
+	if err != nil || packet.HardwareAddr().String() != target.MacAddress {
+		t.Errorf("Expected magic packet for %s, got %x", target.MacAddress, buf[:n])
+	}
+
+	// Requests signed with another secret should be rejected
+	target.RelaySecret = "wrong"
+	if err := wol.WakeDevice(wol.TransportSender{}, target); err == nil {
+		t.Errorf("Expected error for wrong secret")
+	}
+}
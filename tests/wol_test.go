@@ -1,7 +1,11 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"testing"
+	"time"
 	"wakey/internal/common/wol"
 )
 
@@ -14,3 +18,213 @@ func TestIsOnline(t *testing.T) {
 		t.Errorf("Expected %s to be online", ipAddress)
 	}
 }
+
+func TestMarshalSecureOn(t *testing.T) {
+	tests := []struct {
+		password string
+		length   int
+	}{
+		{"", 102},
+		{"192.168.1.1", 106},
+		{"aa:bb:cc:dd", 106},
+		{"00-11-22-33-44-55", 108},
+	}
+
+	for _, tt := range tests {
+		packet, err := wol.New("00:11:22:33:44:55", tt.password)
+		if err != nil {
+			t.Fatalf("New(%q) returned error: %v", tt.password, err)
+		}
+
+		data, err := packet.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+
+		if len(data) != tt.length {
+			t.Errorf("Expected %d bytes for password %q, got %d", tt.length, tt.password, len(data))
+		}
+	}
+
+	// Invalid passwords should be rejected
+	for _, password := range []string{"00:11:22", "1.2.3", "zz:zz:zz:zz"} {
+		if _, err := wol.New("00:11:22:33:44:55", password); err == nil {
+			t.Errorf("Expected error for password %q", password)
+		}
+	}
+}
+
+func TestWakeGroupReportsEveryDevice(t *testing.T) {
+	targets := []wol.Target{
+		{ID: "nas", MacAddress: "not a mac"},
+		{ID: "pc", MacAddress: "00:11:22"},
+	}
+
+	results := wol.WakeGroup(&wol.Recorder{}, targets)
+	if len(results) != len(targets) {
+		t.Fatalf("Expected %d results, got %d", len(targets), len(results))
+	}
+
+	// Every target should be attempted and reported in order
+	for i, result := range results {
+		if result.DeviceID != targets[i].ID {
+			t.Errorf("Expected result %d for %s, got %s", i, targets[i].ID, result.DeviceID)
+		}
+		if result.Err == nil {
+			t.Errorf("Expected an error for %s", result.DeviceID)
+		}
+	}
+}
+
+func TestMarshalHardwareAddresses(t *testing.T) {
+	tests := []struct {
+		mac    string
+		hwAddr []byte
+	}{
+		{"00:11:22:33:44:55", []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}},
+		{"00-11-22-33-44-55-66-77", []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77}},
+	}
+
+	for _, tt := range tests {
+		// Build the expected packet by hand
+		expected := bytes.Repeat([]byte{0xFF}, 6)
+		expected = append(expected, bytes.Repeat(tt.hwAddr, 16)...)
+
+		packet, err := wol.New(tt.mac)
+		if err != nil {
+			t.Fatalf("New(%q) returned error: %v", tt.mac, err)
+		}
+
+		data, err := packet.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+
+		if !bytes.Equal(data, expected) {
+			t.Errorf("Unexpected packet for %s: %x", tt.mac, data)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		mac      string
+		password string
+	}{
+		{"00:11:22:33:44:55", ""},
+		{"00:11:22:33:44:55", "192.168.1.1"},
+		{"00:11:22:33:44:55", "aa:bb:cc:dd:ee:ff"},
+		{"00:11:22:33:44:55:66:77", "aa:bb:cc:dd:ee:ff"},
+	}
+
+	for _, tt := range tests {
+		packet, err := wol.New(tt.mac, tt.password)
+		if err != nil {
+			t.Fatalf("New(%q) returned error: %v", tt.mac, err)
+		}
+
+		data, err := packet.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal returned error: %v", err)
+		}
+
+		// Decoding the packet should give back the address and password
+		parsed, err := wol.Parse(data)
+		if err != nil {
+			t.Fatalf("Parse returned error for %s: %v", tt.mac, err)
+		}
+
+		if parsed.HardwareAddr().String() != tt.mac {
+			t.Errorf("Expected address %s, got %s", tt.mac, parsed.HardwareAddr())
+		}
+
+		if wol.FormatPassword(parsed.Password()) != tt.password {
+			t.Errorf("Expected password %q, got %q", tt.password, wol.FormatPassword(parsed.Password()))
+		}
+	}
+
+	// Packets that aren't magic packets should be rejected
+	invalid := [][]byte{
+		nil,
+		bytes.Repeat([]byte{0x00}, 102),
+		append(bytes.Repeat([]byte{0xFF}, 6), bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}, 15)...),
+	}
+	for _, data := range invalid {
+		if _, err := wol.Parse(data); err == nil {
+			t.Errorf("Expected error for %x", data)
+		}
+	}
+}
+
+func TestWakeDeviceContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// No packets should be sent once the context is cancelled
+	recorder := &wol.Recorder{}
+	target := wol.Target{MacAddress: "00:11:22:33:44:55", Repeat: 3}
+	if err := wol.WakeDeviceContext(ctx, recorder, target); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if len(recorder.Packets()) != 0 {
+		t.Errorf("Expected no packets for a cancelled context, got %d", len(recorder.Packets()))
+	}
+
+	// Pinging with a cancelled context should return right away
+	if wol.IsOnlineContext(ctx, "127.0.0.1") {
+		t.Errorf("Expected offline for cancelled context")
+	}
+}
+
+func TestWakeDeviceRepeat(t *testing.T) {
+	// Execute: Send a burst of three packets
+	recorder := &wol.Recorder{}
+	target := wol.Target{MacAddress: "00:11:22:33:44:55", Repeat: 3, Interval: 20 * time.Millisecond}
+	start := time.Now()
+	if err := wol.WakeDevice(recorder, target); err != nil {
+		t.Fatalf("WakeDevice returned an error: %v", err)
+	}
+
+	// Verify: Every packet was sent and spaced by the interval
+	if len(recorder.Packets()) != 3 {
+		t.Errorf("Expected 3 packets, got %d", len(recorder.Packets()))
+	}
+	if elapsed := time.Since(start); elapsed < 2*target.Interval {
+		t.Errorf("Expected the packets to be spaced by %s, took %s", target.Interval, elapsed)
+	}
+
+	// Verify: A relay repeats the packets itself, so it gets a single request
+	recorder = &wol.Recorder{}
+	target.Relay = "relay.lan:4343"
+	if err := wol.WakeDevice(recorder, target); err != nil {
+		t.Fatalf("WakeDevice returned an error: %v", err)
+	}
+	if len(recorder.Packets()) != 1 {
+		t.Errorf("Expected 1 packet for a relay, got %d", len(recorder.Packets()))
+	}
+}
+
+func TestWakeDeviceAddress(t *testing.T) {
+	tests := []struct {
+		target wol.Target
+		want   string
+	}{
+		{wol.Target{MacAddress: "00:11:22:33:44:55"}, "255.255.255.255:9"},
+		{wol.Target{MacAddress: "00:11:22:33:44:55", BroadcastAddress: "10.0.20.255"}, "10.0.20.255:9"},
+		{wol.Target{MacAddress: "00:11:22:33:44:55", Port: 7}, "255.255.255.255:7"},
+	}
+
+	for _, tt := range tests {
+		recorder := &wol.Recorder{}
+		if err := wol.WakeDevice(recorder, tt.target); err != nil {
+			t.Fatalf("WakeDevice returned an error: %v", err)
+		}
+
+		// Verify: Empty settings fall back to the default broadcast address and port
+		packets := recorder.Packets()
+		if len(packets) != 1 || packets[0].Target.Address() != tt.want {
+			t.Errorf("Expected a packet to %s, got %+v", tt.want, packets)
+		}
+	}
+}